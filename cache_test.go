@@ -0,0 +1,128 @@
+package jsonapi
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testCacheAuthor struct {
+	ID   uint64 `jsonapi:"id,cache-authors"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type testCacheBook struct {
+	ID      uint64             `jsonapi:"id,cache-books"`
+	Title   string             `jsonapi:"attr,title"`
+	Authors []*testCacheAuthor `jsonapi:"rel,authors"`
+}
+
+type testCacheInvalid struct {
+	ID     uint64             `jsonapi:"id,cache-invalids"`
+	Title  string             `jsonapi:"attr,title"`
+	Broken *testInvalidMember `jsonapi:"rel,broken"`
+}
+
+type testCacheTyped struct {
+	Key  string `jsonapi:"attr,key"`
+	kind string
+}
+
+func (c testCacheTyped) JSONType() string {
+	if c.kind != "" {
+		return c.kind
+	}
+	return "typed"
+}
+
+func (c testCacheTyped) JSONID() string {
+	return "Key"
+}
+
+func TestPrepare(t *testing.T) {
+	assertNil(t, Prepare(&testCacheBook{}, []testPost{}))
+	assertEqual(t, "cache-authors", types.get(reflect.TypeOf(testCacheAuthor{})).stype)
+
+	err := Prepare(testCacheInvalid{})
+	assertEqual(t, `jsonapi: invalid member name "name!" of jsonapi.testInvalidMember.Name: '!' is not allowed`, err.Error())
+
+	assertEqual(t, "jsonapi: can't prepare int, struct expected", Prepare(1).Error())
+}
+
+type testCacheNested struct {
+	City string `json:"city" validate:"requird"`
+}
+
+func TestPrepareInvalidTags(t *testing.T) {
+	for _, item := range []interface{}{
+		&struct {
+			ID   uint64 `jsonapi:"id,rules"`
+			Name string `jsonapi:"attr,name" validate:"requird"`
+		}{},
+		&struct {
+			ID   uint64 `jsonapi:"id,sizes"`
+			Name string `jsonapi:"attr,name" validate:"min=x"`
+		}{},
+		&struct {
+			ID   uint64 `jsonapi:"id,transforms"`
+			Name string `jsonapi:"attr,name" transform:"reverse"`
+		}{},
+		&struct {
+			ID  uint64    `jsonapi:"id,formats"`
+			Day time.Time `jsonapi:"attr,day,format=week"`
+		}{},
+	} {
+		err := Prepare(item)
+		assertEqual(t, true, err != nil, fmt.Sprintf("%T", item))
+		_, err = Marshal(item)
+		assertEqual(t, true, err != nil, fmt.Sprintf("%T", item))
+	}
+
+	err := Prepare(&struct {
+		ID      uint64          `jsonapi:"id,nested"`
+		Address testCacheNested `jsonapi:"attr,address"`
+	}{})
+	assertEqual(t, `jsonapi: invalid tag of jsonapi.testCacheNested.City: unknown validation rule 'requird'`, err.Error())
+}
+
+func TestTypeMetadataDependsOnType(t *testing.T) {
+	res, err := Marshal(&testCacheTyped{Key: "k", kind: "other"})
+	assertNil(t, err)
+	assertEqual(t, `{"id":"k","type":"typed","attributes":{"key":"k"}}`, string(res))
+}
+
+func TestConcurrentCache(t *testing.T) {
+	types.reset()
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b := testCacheBook{ID: uint64(i), Title: "t", Authors: []*testCacheAuthor{{ID: 1}}}
+			res, err := Marshal(&b)
+			if err != nil {
+				errs <- err
+				return
+			}
+			d := testCacheBook{}
+			if err := Unmarshal([]byte(`{"data":`+string(res)+`}`), &d); err != nil {
+				errs <- err
+				return
+			}
+			if d.Title != "t" || len(d.Authors) != 1 {
+				errs <- fmt.Errorf("unexpected result %+v", d)
+			}
+			if i%8 == 0 {
+				errs <- Prepare(&testCacheBook{}, &testStruct{})
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assertNil(t, err)
+	}
+}
@@ -0,0 +1,256 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Codec encodes and decodes attribute and meta values.
+// Encode receives field value and returns valid JSON, Decode receives JSON and pointer to field.
+type Codec struct {
+	Encode func(value interface{}) ([]byte, error)
+	Decode func(b []byte, ptr interface{}) error
+	// Schema is JSON Schema of encoded value used by schema export.
+	// String option quotes encoded value unless schema type is string.
+	Schema Schema
+	// Type is type of values accepted by codec. Format used for field of other type
+	// is invalid tag. Pointer fields are checked by element type, nil accepts any type.
+	Type reflect.Type
+}
+
+var codecs = codecRegistry{
+	types: make(map[reflect.Type]Codec),
+	formats: map[string]Codec{
+		"unix":      timeCodec(time.Time.Unix, func(n int64) time.Time { return time.Unix(n, 0).UTC() }),
+		"unixmilli": timeCodec(time.Time.UnixMilli, func(n int64) time.Time { return time.UnixMilli(n).UTC() }),
+		"date":      layoutCodec("2006-01-02", Schema{"type": "string", "format": "date"}),
+		"duration":  durationCodec,
+	},
+}
+
+type codecRegistry struct {
+	sync.RWMutex
+	types   map[reflect.Type]Codec
+	formats map[string]Codec
+}
+
+// RegisterCodec registers codec used for every attribute and meta field of sample type
+// without format option. Codecs must be registered before types using them are marshalled or unmarshalled.
+// 	jsonapi.RegisterCodec(decimal.Decimal{}, jsonapi.Codec{
+// 		Encode: func(v interface{}) ([]byte, error) { return []byte(v.(decimal.Decimal).String()), nil },
+// 		Decode: func(b []byte, p interface{}) error { return p.(*decimal.Decimal).UnmarshalJSON(b) },
+// 	})
+func RegisterCodec(sample interface{}, c Codec) {
+	t := reflect.TypeOf(sample)
+	if t == nil || c.Encode == nil || c.Decode == nil {
+		panic(fmt.Sprintf("jsonapi: invalid codec for %T", sample))
+	}
+	codecs.Lock()
+	codecs.types[t] = c
+	codecs.Unlock()
+}
+
+// RegisterFormat registers codec selected with format option.
+// Built-in formats are unix, unixmilli and date for time.Time and duration for time.Duration,
+// other time layouts are registered with LayoutCodec.
+// 	Created time.Time `jsonapi:"attr,created,format=unix"`
+func RegisterFormat(name string, c Codec) {
+	if name == "" || strings.ContainsRune(name, ',') || c.Encode == nil || c.Decode == nil {
+		panic(fmt.Sprintf("jsonapi: invalid format '%s'", name))
+	}
+	codecs.Lock()
+	codecs.formats[name] = c
+	codecs.Unlock()
+}
+
+// LayoutCodec returns codec of time.Time values formatted with layout
+// 	jsonapi.RegisterFormat("rfc1123", jsonapi.LayoutCodec(time.RFC1123))
+// 	Expires time.Time `jsonapi:"attr,expires,format=rfc1123"`
+func LayoutCodec(layout string) Codec {
+	return layoutCodec(layout, Schema{"type": "string"})
+}
+
+// lookup returns codec for format or field type. Pointer fields use codec of element type.
+func (r *codecRegistry) lookup(format string, t reflect.Type) (*Codec, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	if format != "" {
+		c, ok := r.formats[format]
+		return &c, ok
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if c, ok := r.types[t]; ok {
+		return &c, true
+	}
+	return nil, true
+}
+
+// quotedType returns structure with single field of type t and string option
+// or nil if string option is not applicable to t like in encoding/json
+func quotedType(t reflect.Type) reflect.Type {
+	ft := t
+	if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	switch ft.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		return nil
+	}
+	return reflect.StructOf([]reflect.StructField{{Name: "V", Type: t, Tag: `json:"v,string"`}})
+}
+
+// encode returns JSON of field value. Value of field with string option
+// is encoded by encoding/json, codec output is encoded as JSON string.
+func (f field) encode(v reflect.Value) ([]byte, error) {
+	switch {
+	case f.quoted != nil:
+		w := reflect.New(f.quoted).Elem()
+		w.Field(0).Set(v)
+		b, err := json.Marshal(w.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return b[len(`{"v":`) : len(b)-1], nil
+	case f.codec == nil:
+		return json.Marshal(v.Interface())
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return []byte("null"), nil
+		}
+		v = v.Elem()
+	}
+	b, err := f.codec.Encode(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(b) {
+		return nil, fmt.Errorf("jsonapi: codec of '%s' returned invalid JSON %.32q", f.name, b)
+	}
+	if !f.quote {
+		return b, nil
+	}
+	return json.Marshal(string(b))
+}
+
+// decode sets field value from JSON
+func (f field) decode(b []byte, v reflect.Value) error {
+	switch {
+	case f.quoted != nil:
+		w := reflect.New(f.quoted)
+		w.Elem().Field(0).Set(v)
+		buf := append(append([]byte(`{"v":`), b...), '}')
+		if err := json.Unmarshal(buf, w.Interface()); err != nil {
+			return err
+		}
+		v.Set(w.Elem().Field(0))
+		return nil
+	case f.codec == nil:
+		return json.Unmarshal(b, v.Addr().Interface())
+	case f.quote:
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		b = []byte(s)
+	}
+	if v.Kind() == reflect.Ptr {
+		if string(bytes.TrimSpace(b)) == "null" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return f.codec.Decode(b, v.Addr().Interface())
+}
+
+func timeCodec(enc func(time.Time) int64, dec func(n int64) time.Time) Codec {
+	return Codec{
+		Encode: func(v interface{}) ([]byte, error) {
+			t, ok := v.(time.Time)
+			if !ok {
+				return nil, fmt.Errorf("jsonapi: time codec can't encode %T", v)
+			}
+			return json.Marshal(enc(t))
+		},
+		Decode: func(b []byte, p interface{}) error {
+			t, ok := p.(*time.Time)
+			if !ok {
+				return fmt.Errorf("jsonapi: time codec can't decode into %T", p)
+			}
+			var n int64
+			if err := json.Unmarshal(b, &n); err != nil {
+				return err
+			}
+			*t = dec(n)
+			return nil
+		},
+		Schema: Schema{"type": "integer"},
+		Type:   timeType,
+	}
+}
+
+func layoutCodec(layout string, s Schema) Codec {
+	return Codec{
+		Encode: func(v interface{}) ([]byte, error) {
+			t, ok := v.(time.Time)
+			if !ok {
+				return nil, fmt.Errorf("jsonapi: time codec can't encode %T", v)
+			}
+			return json.Marshal(t.Format(layout))
+		},
+		Decode: func(b []byte, p interface{}) error {
+			t, ok := p.(*time.Time)
+			if !ok {
+				return fmt.Errorf("jsonapi: time codec can't decode into %T", p)
+			}
+			var s string
+			if err := json.Unmarshal(b, &s); err != nil {
+				return err
+			}
+			v, err := time.Parse(layout, s)
+			*t = v
+			return err
+		},
+		Schema: s,
+		Type:   timeType,
+	}
+}
+
+var durationCodec = Codec{
+	Encode: func(v interface{}) ([]byte, error) {
+		d, ok := v.(time.Duration)
+		if !ok {
+			return nil, fmt.Errorf("jsonapi: duration codec can't encode %T", v)
+		}
+		return json.Marshal(d.String())
+	},
+	Decode: func(b []byte, p interface{}) error {
+		d, ok := p.(*time.Duration)
+		if !ok {
+			return fmt.Errorf("jsonapi: duration codec can't decode into %T", p)
+		}
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		v, err := time.ParseDuration(s)
+		*d = v
+		return err
+	},
+	Schema: Schema{"type": "string", "format": "duration"},
+	Type:   reflect.TypeOf(time.Duration(0)),
+}
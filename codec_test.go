@@ -0,0 +1,146 @@
+package jsonapi
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testMoney struct {
+	Cents int64
+}
+
+type testIP [4]byte
+
+type testCodecs struct {
+	ID      uint64        `jsonapi:"id,codecs"`
+	Created time.Time     `jsonapi:"attr,created,format=unix"`
+	Day     *time.Time    `jsonapi:"attr,day,format=date"`
+	Timeout time.Duration `jsonapi:"attr,timeout,format=duration"`
+	Price   testMoney     `jsonapi:"attr,price"`
+	Total   *testMoney    `jsonapi:"attr,total"`
+}
+
+func init() {
+	RegisterCodec(testMoney{}, Codec{
+		Encode: func(v interface{}) ([]byte, error) {
+			m := v.(testMoney)
+			return []byte(fmt.Sprintf(`"%d.%02d"`, m.Cents/100, m.Cents%100)), nil
+		},
+		Decode: func(b []byte, p interface{}) error {
+			s, err := strconv.Unquote(string(b))
+			if err != nil {
+				return err
+			}
+			f, err := strconv.ParseFloat(s, 64)
+			p.(*testMoney).Cents = int64(f*100 + 0.5)
+			return err
+		},
+		Schema: Schema{"type": "string"},
+	})
+	RegisterCodec(testIP{}, Codec{
+		Encode: func(v interface{}) ([]byte, error) {
+			ip := v.(testIP)
+			return []byte(fmt.Sprintf("%d.%d.%d.%d", ip[0], ip[1], ip[2], ip[3])), nil
+		},
+		Decode: func(b []byte, p interface{}) error {
+			return nil
+		},
+	})
+}
+
+func TestCodecs(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	s := testCodecs{ID: 1, Created: time.Unix(1577923200, 0), Day: &day, Timeout: 90 * time.Second, Price: testMoney{1050}}
+	want := `{"id":"1","type":"codecs","attributes":{"created":1577923200,"day":"2020-01-02","timeout":"1m30s","price":"10.50","total":null}}`
+	res, err := Marshal(&s)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	d := testCodecs{}
+	b := []byte(`{"data":{"type":"codecs","attributes":{"created":1577923200,"day":"2020-01-02","timeout":"1m30s","price":"10.50","total":"1.00"}}}`)
+	assertNil(t, Unmarshal(b, &d))
+	assertEqual(t, int64(1577923200), d.Created.Unix())
+	assertEqual(t, day, *d.Day)
+	assertEqual(t, 90*time.Second, d.Timeout)
+	assertEqual(t, testMoney{1050}, d.Price)
+	assertEqual(t, testMoney{100}, *d.Total)
+
+	rs, err := ResourceSchema(&s, nil)
+	assertNil(t, err)
+	attrs := rs["properties"].(Schema)["attributes"].(Schema)["properties"].(Schema)
+	assertEqual(t, Schema{"type": "integer"}, attrs["created"])
+	assertEqual(t, Schema{"type": []string{"string", "null"}}, attrs["total"])
+}
+
+func TestCodecInvalidJSON(t *testing.T) {
+	s := struct {
+		ID uint64 `jsonapi:"id,hosts"`
+		IP testIP `jsonapi:"attr,ip"`
+	}{ID: 1, IP: testIP{1, 2, 3, 4}}
+	_, err := Marshal(&s)
+	assertEqual(t, `jsonapi: codec of 'ip' returned invalid JSON "1.2.3.4"`, err.Error())
+}
+
+func TestUnixMilliRange(t *testing.T) {
+	s := struct {
+		ID      uint64    `jsonapi:"id,times"`
+		Created time.Time `jsonapi:"attr,created,format=unixmilli"`
+		Started time.Time `jsonapi:"attr,started,format=unix"`
+	}{ID: 1, Created: time.Date(1500, 1, 1, 0, 0, 0, 0, time.UTC), Started: time.Date(2500, 1, 1, 0, 0, 0, 0, time.UTC)}
+	res, err := Marshal(&s)
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"times","attributes":{"created":-14831769600000,"started":16725225600}}`, string(res))
+
+	d := s
+	d.Created, d.Started = time.Time{}, time.Time{}
+	assertNil(t, Unmarshal([]byte(`{"data":{"type":"times","attributes":{"created":-14831769600000,"started":16725225600}}}`), &d))
+	assertEqual(t, s.Created, d.Created)
+	assertEqual(t, s.Started, d.Started)
+}
+
+type testMisformatted struct {
+	ID   uint64 `jsonapi:"id,misformatted"`
+	Name string `jsonapi:"attr,name,format=unix"`
+}
+
+func TestCodecType(t *testing.T) {
+	_, err := Marshal(&testMisformatted{ID: 1})
+	assertEqual(t, "jsonapi: invalid tag of jsonapi.testMisformatted.Name: format 'unix' requires time.Time, not string", err.Error())
+}
+
+func TestLayoutCodec(t *testing.T) {
+	RegisterFormat("test-rfc1123", LayoutCodec(time.RFC1123))
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	s := struct {
+		ID      uint64     `jsonapi:"id,expiring"`
+		Expires *time.Time `jsonapi:"attr,expires,format=test-rfc1123"`
+	}{ID: 1, Expires: &day}
+	res, err := Marshal(&s)
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"expiring","attributes":{"expires":"Thu, 02 Jan 2020 00:00:00 UTC"}}`, string(res))
+
+	d := s
+	d.Expires = nil
+	assertNil(t, Unmarshal([]byte(`{"data":`+string(res)+`}`), &d))
+	assertEqual(t, day, *d.Expires)
+}
+
+func TestCodecStringOption(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	s := struct {
+		ID      uint64    `jsonapi:"id,days"`
+		Day     time.Time `jsonapi:"attr,day,format=date,string"`
+		Created time.Time `jsonapi:"attr,created,format=unix,string"`
+	}{ID: 1, Day: day, Created: day}
+	res, err := Marshal(&s)
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"days","attributes":{"day":"2020-01-02","created":"1577923200"}}`, string(res))
+
+	d := s
+	d.Day, d.Created = time.Time{}, time.Time{}
+	assertNil(t, Unmarshal([]byte(`{"data":`+string(res)+`}`), &d))
+	assertEqual(t, day, d.Day)
+	assertEqual(t, day, d.Created)
+}
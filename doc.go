@@ -10,17 +10,17 @@ Example:
   )
 
   type Post struct {
-  	ID       uint64        `jsonapi:"id,users"`
-  	Name     string        `jsonapi:"attr,name"`
-  	Age      uint32        `jsonapi:"attr,age,string,readonly"` // this will be marshalled as string and will be ignored on unmarshal
-  	SelfLink string        `jsonapi:"link,self"`
-  	Comments jsonapi.Links `jsonapi:"rellink,comments"`
+  	ID       uint64           `jsonapi:"id,users"`
+  	Name     string           `jsonapi:"attr,name"`
+  	Age      uint32           `jsonapi:"attr,age,string,readonly"` // this will be marshalled as string and will be ignored on unmarshal
+  	SelfLink string           `jsonapi:"link,self"`
+  	Comments jsonapi.Relation `jsonapi:"rel,comments"`
   }
 
   // BeforeMarshalJSONAPI will be executed before marshalling
   func (p *Post) BeforeMarshalJSONAPI() error {
   	p.SelfLink = fmt.Sprintf("/api/posts/%d", p.ID)
-  	p.Comments.Related = fmt.Sprintf("/api/posts/%d/comments", p.ID)
+  	p.Comments.Links.Related = jsonapi.Link{Href: fmt.Sprintf("/api/posts/%d/comments", p.ID)}
   	return nil
   }
 
@@ -31,6 +31,80 @@ Example:
   	return v.Verify()
   }
 
+Attributes can be validated declaratively, rules are checked after unmarshal and errors
+are returned as jsonapi.Errors with pointers to attributes:
+
+  Name  string `jsonapi:"attr,name" validate:"required,min=3,max=50"`
+  Email string `jsonapi:"attr,email" validate:"required,email"`
+  Role  string `jsonapi:"attr,role" validate:"oneof=admin user"`
+
+Attributes are normalized on unmarshal with transforms registered by name,
+trim, lower and upper are built in:
+
+  Email string `jsonapi:"attr,email" transform:"trim,lower" validate:"required,email"`
+
+Values are encoded with codecs registered for Go types with RegisterCodec or selected
+with format option, unix, unixmilli, date and duration formats are built in:
+
+  Created time.Time `jsonapi:"attr,created,format=unix"`
+
+Other time layouts are registered as formats with LayoutCodec:
+
+  jsonapi.RegisterFormat("rfc1123", jsonapi.LayoutCodec(time.RFC1123))
+
+Instead of setting links in BeforeMarshalJSONAPI they can be generated for every resource and relationship:
+
+  jsonapi.SetLinker(&jsonapi.Linker{BaseURL: "https://example.com/api"})
+  // "links":{"self":"https://example.com/api/users/1"}
+  // "comments":{"links":{"self":"https://example.com/api/users/1/relationships/comments","related":"https://example.com/api/users/1/comments"}}
+
+Relationship fields holding resources with id or interfaces are marshalled as resource linkage
+using type and id of every item, Relation and other values are marshalled as is. To unmarshal polymorphic relationships register resource types:
+
+  type Comment struct {
+  	ID          uint64        `jsonapi:"id,comments"`
+  	Commentable interface{}   `jsonapi:"rel,commentable"` // *Post or *Photo
+  	Tags        []interface{} `jsonapi:"rel,tags"`
+  }
+
+  jsonapi.RegisterType(&Post{}, &Photo{}, &Tag{})
+
+Fields are selected for caller roles with scope tag which may differ for reading and writing,
+scope of one direction is used for both unless the other direction is set:
+
+  Salary int `jsonapi:"attr,salary" scope:"read:admin,owner write:admin"`
+
+  b, err := jsonapi.MarshalScope(user, jsonapi.Scope{Roles: []string{"owner"}, Strict: true})
+  err = jsonapi.UnmarshalScope(body, user, jsonapi.Roles("admin"))
+
+Strict scope denies fields without scope tag so new fields are not exposed by accident.
+Access depending on resource itself is checked with AttributeReader and AttributeWriter
+interfaces receiving context passed to MarshalContext and UnmarshalContext.
+BeforeMarshalJSONAPI and AfterUnmarshalJSONAPI hooks may accept the same context:
+
+  func (p *Post) BeforeMarshalJSONAPI(ctx context.Context) error
+
+Names of attributes and relationships without name in tag and resource types without type
+follow naming strategy:
+
+  jsonapi.SetNaming(jsonapi.Naming{Member: jsonapi.CamelCase, Type: jsonapi.KebabCase, Plural: true})
+
+Tags, type and member names are validated when type is first used. Unknown rules, formats,
+transforms or scopes and invalid, reserved or duplicate names make marshal and unmarshal
+return error, or panic after jsonapi.SetDevelopment(true).
+Metadata of types is cached and safe for concurrent use. Prepare builds and validates it
+at startup together with types of relationships:
+
+  if err := jsonapi.Prepare(&Post{}, &Comment{}); err != nil {
+  	log.Fatal(err)
+  }
+
+SetNaming drops cached metadata, so it must be called before Prepare.
+
+JSON Schema and OpenAPI 3.1 components are generated from the same tags:
+
+  s, err := jsonapi.ResourceSchema(&Post{}, nil)
+  c, err := jsonapi.OpenAPIComponents(nil, &Post{}, &Comment{}) // posts, posts-request, posts-document, ...
 
 */
 package jsonapi
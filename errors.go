@@ -1,41 +1,129 @@
 package jsonapi
 
-import "strings"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+)
 
 var (
 	// ErrorRecordNotFound returns Error for record not found behaviour
 	ErrorRecordNotFound = Error{
+		Code:   CodeRecordNotFound,
 		Status: "404",
 		Title:  "Record Not Found",
 		Detail: "The record you are looking for does not exist",
+		msg:    &message{title: CodeRecordNotFound, detail: CodeRecordNotFound},
 	}
 	// ErrorPageNotFound returns Error for page not found behaviour
 	ErrorPageNotFound = Error{
+		Code:   CodePageNotFound,
 		Status: "404",
 		Title:  "Page Not Found",
 		Detail: "The page you are looking for does not exist",
+		msg:    &message{title: CodePageNotFound, detail: CodePageNotFound},
 	}
 
 	// ErrorUnauthorized returns Error for unauthorized request
 	ErrorUnauthorized = Error{
+		Code:   CodeUnauthorized,
 		Status: "401",
 		Title:  "Unauthorized Request",
 		Detail: "You are forbidden from accessing this page",
+		msg:    &message{title: CodeUnauthorized, detail: CodeUnauthorized},
 	}
 )
 
+var policy = errorPolicyStore{}
+
+// defaultHiddenDetail is Error.Detail of hidden errors if ErrorPolicy.Detail is empty
+const defaultHiddenDetail = "An unexpected error occurred"
+
+type errorPolicyStore struct {
+	sync.RWMutex
+	p ErrorPolicy
+}
+
+// ErrorPolicy defines how errors which are not Error or Errors are exposed to clients.
+// By default original error message is kept in Error.Detail.
+// Original error is always available with errors.Unwrap for logging.
+type ErrorPolicy struct {
+	// Hide replaces original error message with Detail
+	Hide bool
+	// Detail is generic message used when original message is hidden
+	Detail string
+	// ID returns correlation id stored in Error.ID. No id is set if nil.
+	ID func(err error) string
+}
+
+// SetErrorPolicy sets policy for converting internal errors
+// 	jsonapi.SetErrorPolicy(jsonapi.ErrorPolicy{Hide: true, Detail: "Something went wrong", ID: jsonapi.CorrelationID})
+func SetErrorPolicy(p ErrorPolicy) {
+	policy.Lock()
+	policy.p = p
+	policy.Unlock()
+}
+
+// CorrelationID returns random id for error
+func CorrelationID(err error) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// internalError converts error into internal Error following ErrorPolicy
+func internalError(err error) Error {
+	policy.RLock()
+	p := policy.p
+	policy.RUnlock()
+
+	detail := err.Error()
+	if p.Hide {
+		detail = firstString(p.Detail, defaultHiddenDetail)
+	}
+	e := ErrorInternal(detail)
+	if p.ID != nil {
+		e.ID = p.ID(err)
+	}
+	e.cause = err
+	return e
+}
+
 // ErrorSource type
 type ErrorSource struct {
-	Pointer string `json:"pointer"`
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	Header    string `json:"header,omitempty"`
+}
+
+// ErrorLinks type
+type ErrorLinks struct {
+	About Link `json:"about,omitempty"`
+	Type  Link `json:"type,omitempty"`
+}
+
+// MarshalJSON marshaller
+func (l ErrorLinks) MarshalJSON() ([]byte, error) {
+	return marshalLinks([]string{"about", "type"}, l.About, l.Type)
 }
 
 // Error type
 type Error struct {
+	ID     string       `json:"id,omitempty"`
+	Links  *ErrorLinks  `json:"links,omitempty"`
 	Code   string       `json:"code,omitempty"`
 	Status string       `json:"status,omitempty"`
 	Source *ErrorSource `json:"source,omitempty"`
 	Title  string       `json:"title,omitempty"`
 	Detail string       `json:"detail,omitempty"`
+	Meta   interface{}  `json:"meta,omitempty"`
+	cause  error
+	msg    *message
 }
 
 // Error returns Detail to implement error interface
@@ -43,6 +131,57 @@ func (e Error) Error() string {
 	return e.Detail
 }
 
+// StatusCode returns error status as int or 0 if status is not valid
+func (e Error) StatusCode() int {
+	c := strToInt(e.Status)
+	if c < 100 || c > 599 {
+		return 0
+	}
+	return c
+}
+
+// Wrap returns copy of Error with original error stored as cause
+// 	return jsonapi.ErrorRecordNotFound.Wrap(sql.ErrNoRows)
+func (e Error) Wrap(cause error) Error {
+	e.cause = cause
+	return e
+}
+
+// Unwrap returns original error
+func (e Error) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is Error with the same status and code,
+// or the same status and title if target has no code
+// 	errors.Is(err, jsonapi.ErrorRecordNotFound)
+func (e Error) Is(target error) bool {
+	var t Error
+	switch v := target.(type) {
+	case Error:
+		t = v
+	case *Error:
+		if v == nil {
+			return false
+		}
+		t = *v
+	default:
+		return false
+	}
+	if t.Code != "" {
+		return e.Status == t.Status && e.Code == t.Code
+	}
+	return e.Status == t.Status && e.Title == t.Title
+}
+
+// ParseErrors decodes errors document received from json api service
+// 	errs, err := jsonapi.ParseErrors(body)
+func ParseErrors(b []byte) (Errors, error) {
+	e := Errors{}
+	err := json.Unmarshal(b, &e)
+	return e, err
+}
+
 // Errors type
 type Errors struct {
 	Errors []Error `json:"errors,omitempty"`
@@ -53,20 +192,49 @@ func (e Errors) HasErrors() bool {
 	return len(e.Errors) > 0
 }
 
-// AddError adds Error to errors
+// AddError adds Error to errors. Errors which are not Error or Errors
+// are converted with registered error mappers or to internal error following ErrorPolicy.
 func (e *Errors) AddError(err error) {
 	if err == nil {
 		return
 	}
 
-	switch err.(type) {
+	switch v := err.(type) {
 	case Error:
-		e.Errors = append(e.Errors, err.(Error))
+		e.Errors = append(e.Errors, v)
+		return
 	case Errors:
-		e.Errors = append(e.Errors, err.(Errors).Errors...)
-	default:
-		e.Errors = append(e.Errors, ErrorInternal(err.Error()))
+		e.Errors = append(e.Errors, v.Errors...)
+		return
 	}
+
+	var errs Errors
+	if errors.As(err, &errs) {
+		e.Errors = append(e.Errors, errs.Errors...)
+		return
+	}
+	var je Error
+	if errors.As(err, &je) {
+		if je.cause == nil {
+			je.cause = err
+		}
+		e.Errors = append(e.Errors, je)
+		return
+	}
+	if je, ok := mappers.convert(err); ok {
+		e.Errors = append(e.Errors, je.Wrap(err))
+		return
+	}
+	e.Errors = append(e.Errors, internalError(err))
+}
+
+// Unwrap returns all errors
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for k := range e.Errors {
+		errs[k] = e.Errors[k]
+	}
+	return errs
 }
 
 // Error returns Detail to implement error interface
@@ -81,36 +249,68 @@ func (e Errors) Error() string {
 // ErrorInternal creating Error for internal error
 func ErrorInternal(details string) Error {
 	return Error{
+		Code:   CodeInternal,
 		Status: "500",
 		Title:  "Internal Server Error",
 		Detail: details,
+		msg:    &message{title: CodeInternal},
 	}
 }
 
 // ErrorInvalidAttribute creating Error for invalid attributes
 func ErrorInvalidAttribute(pointer, details string) Error {
 	return Error{
+		Code:   CodeInvalidAttribute,
 		Status: "422",
 		Source: &ErrorSource{Pointer: "/data/attributes/" + pointer},
 		Title:  "Invalid Attribute",
 		Detail: details,
+		msg:    &message{title: CodeInvalidAttribute},
 	}
 }
 
 // ErrorBadRequest creating Error for inprocessible entries
 func ErrorBadRequest(details string) Error {
 	return Error{
+		Code:   CodeBadRequest,
 		Status: "400",
 		Title:  "Bad Request",
 		Detail: details,
+		msg:    &message{title: CodeBadRequest},
 	}
 }
 
 // ErrorForbidden creating Error for forbidden entries
 func ErrorForbidden(details string) Error {
 	return Error{
+		Code:   CodeForbidden,
 		Status: "403",
 		Title:  "Forbidden",
 		Detail: details,
+		msg:    &message{title: CodeForbidden},
+	}
+}
+
+// ErrorInvalidParameter creating Error for invalid query parameter
+func ErrorInvalidParameter(parameter, details string) Error {
+	return Error{
+		Code:   CodeInvalidParameter,
+		Status: "400",
+		Source: &ErrorSource{Parameter: parameter},
+		Title:  "Invalid Query Parameter",
+		Detail: details,
+		msg:    &message{title: CodeInvalidParameter},
+	}
+}
+
+// ErrorInvalidHeader creating Error for invalid request header
+func ErrorInvalidHeader(header, details string) Error {
+	return Error{
+		Code:   CodeInvalidHeader,
+		Status: "400",
+		Source: &ErrorSource{Header: header},
+		Title:  "Invalid Header",
+		Detail: details,
+		msg:    &message{title: CodeInvalidHeader},
 	}
 }
@@ -0,0 +1,67 @@
+package jsonapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorsRoundTrip(t *testing.T) {
+	e := Errors{}
+	e.AddError(ErrorInvalidParameter("page[size]", "must be positive"))
+	e.AddError(ErrorInvalidHeader("Accept", "unsupported media type parameters"))
+	e.AddError(Error{
+		ID:     "e1",
+		Links:  &ErrorLinks{About: Link{Href: "/docs/e1"}, Type: Link{Href: "/types/quota"}},
+		Status: "429",
+		Meta:   map[string]interface{}{"retry": "60s"},
+	})
+
+	want := `{"errors":[` +
+		`{"code":"invalid_parameter","status":"400","source":{"parameter":"page[size]"},"title":"Invalid Query Parameter","detail":"must be positive"},` +
+		`{"code":"invalid_header","status":"400","source":{"header":"Accept"},"title":"Invalid Header","detail":"unsupported media type parameters"},` +
+		`{"id":"e1","links":{"about":"/docs/e1","type":"/types/quota"},"status":"429","meta":{"retry":"60s"}}]}`
+	b, err := json.Marshal(e)
+	assertNil(t, err)
+	assertEqual(t, want, string(b))
+
+	e1, err := ParseErrors(b)
+	assertNil(t, err)
+	b1, err := json.Marshal(e1)
+	assertNil(t, err)
+	assertEqual(t, string(b), string(b1))
+}
+
+func TestErrorsWrap(t *testing.T) {
+	errDB := errors.New("db: connection refused")
+
+	e := Errors{}
+	e.AddError(errDB)
+	assertEqual(t, 1, len(e.Errors))
+	assertEqual(t, "500", e.Errors[0].Status)
+	assertEqual(t, "db: connection refused", e.Errors[0].Detail)
+	assertEqual(t, "", e.Errors[0].ID)
+	assertEqual(t, true, errors.Is(e, errDB))
+	assertEqual(t, errDB, errors.Unwrap(e.Errors[0]))
+
+	e.AddError(fmt.Errorf("loading post: %w", ErrorRecordNotFound.Wrap(sql.ErrNoRows)))
+	assertEqual(t, 2, len(e.Errors))
+	assertEqual(t, "404", e.Errors[1].Status)
+	assertEqual(t, true, errors.Is(e, ErrorRecordNotFound))
+	assertEqual(t, true, errors.Is(e, sql.ErrNoRows))
+	assertEqual(t, false, errors.Is(e, ErrorUnauthorized))
+
+	var je Error
+	assertEqual(t, true, errors.As(fmt.Errorf("wrapped: %w", e), &je))
+	assertEqual(t, "500", je.Status)
+
+	SetErrorPolicy(ErrorPolicy{Hide: true, ID: CorrelationID})
+	defer SetErrorPolicy(ErrorPolicy{})
+	e = Errors{}
+	e.AddError(errDB)
+	assertEqual(t, "An unexpected error occurred", e.Errors[0].Detail)
+	assertEqual(t, 32, len(e.Errors[0].ID))
+	assertEqual(t, errDB, errors.Unwrap(e.Errors[0]))
+}
@@ -0,0 +1,121 @@
+package jsonapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// BeforeUnmarshaler interface is executed before any field is set on unmarshal.
+// Returned error rejects payload.
+// 	func (p *Post) BeforeUnmarshalJSONAPI() error {
+// 		p.previous = *p
+// 		return nil
+// 	}
+type BeforeUnmarshaler interface {
+	BeforeUnmarshalJSONAPI() error
+}
+
+// AfterMarshaler interface receives marshalled resource object of tagged structure
+// or ResourceMarshaler and returns resource object written to output
+// 	func (p *Post) AfterMarshalJSONAPI(b []byte) ([]byte, error) {
+// 		return bytes.Replace(b, []byte(`"secret"`), []byte(`"***"`), -1), nil
+// 	}
+type AfterMarshaler interface {
+	AfterMarshalJSONAPI(b []byte) ([]byte, error)
+}
+
+var (
+	beforeUnmarshalerType = reflect.TypeOf(new(BeforeUnmarshaler)).Elem()
+	afterMarshalerType    = reflect.TypeOf(new(AfterMarshaler)).Elem()
+)
+
+// TransformFunc returns attribute value transformed on unmarshal.
+// Returned value must be assignable or convertible to attribute type.
+type TransformFunc func(value interface{}) (interface{}, error)
+
+var transforms = transformRegistry{m: map[string]TransformFunc{
+	"trim":  stringFunc(strings.TrimSpace),
+	"lower": stringFunc(strings.ToLower),
+	"upper": stringFunc(strings.ToUpper),
+}}
+
+type transformRegistry struct {
+	sync.RWMutex
+	m map[string]TransformFunc
+}
+
+func (r *transformRegistry) lookup(name string) (TransformFunc, bool) {
+	r.RLock()
+	fn, ok := r.m[name]
+	r.RUnlock()
+	return fn, ok
+}
+
+// RegisterTransform registers named transform for transform tags. Transforms
+// are applied in tag order after attribute is decoded and before it is validated.
+// Built-in transforms are trim, lower and upper.
+// Transforms must be registered before types using them are marshalled or unmarshalled.
+// 	jsonapi.RegisterTransform("digits", func(v interface{}) (interface{}, error) {
+// 		return digitsOnly(v.(string)), nil
+// 	})
+// 	Email string `jsonapi:"attr,email" transform:"trim,lower"`
+func RegisterTransform(name string, fn TransformFunc) {
+	if name == "" || strings.ContainsRune(name, ',') || fn == nil {
+		panic(fmt.Sprintf("jsonapi: invalid transform '%s'", name))
+	}
+	transforms.Lock()
+	transforms.m[name] = fn
+	transforms.Unlock()
+}
+
+// parseTransforms parses transform tag
+func parseTransforms(fd reflect.StructField) ([]TransformFunc, error) {
+	tag := fd.Tag.Get("transform")
+	if tag == "" {
+		return nil, nil
+	}
+	res := []TransformFunc{}
+	for _, name := range strings.Split(tag, ",") {
+		fn, ok := transforms.lookup(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown transform '%s'", name)
+		}
+		res = append(res, fn)
+	}
+	return res, nil
+}
+
+// transform applies field transforms to value
+func (f field) transform(v reflect.Value) error {
+	for _, fn := range f.transforms {
+		res, err := fn(v.Interface())
+		if err != nil {
+			return err
+		}
+		rv := reflect.ValueOf(res)
+		switch {
+		case !rv.IsValid():
+			rv = reflect.Zero(v.Type())
+		case rv.Type().AssignableTo(v.Type()):
+		case rv.Type().ConvertibleTo(v.Type()):
+			rv = rv.Convert(v.Type())
+		default:
+			return fmt.Errorf("jsonapi: can't assign transformed %T to attribute '%s'", res, f.name)
+		}
+		v.Set(rv)
+	}
+	return nil
+}
+
+// stringFunc returns transform applying fn to string values
+func stringFunc(fn func(string) string) TransformFunc {
+	return func(v interface{}) (interface{}, error) {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.String {
+			return v, nil
+		}
+		return fn(rv.String()), nil
+	}
+}
@@ -0,0 +1,101 @@
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type testCtxHooks struct {
+	ID   uint64 `jsonapi:"id,hooks"`
+	Name string `jsonapi:"attr,name"`
+}
+
+func (h *testCtxHooks) BeforeMarshalJSONAPI(ctx context.Context) error {
+	h.Name, _ = ctx.Value(testCtxKey{}).(string)
+	return nil
+}
+
+func (h *testCtxHooks) AfterUnmarshalJSONAPI(ctx context.Context) error {
+	if ctx.Value(testCtxKey{}) == "deny" {
+		return ErrorForbidden("denied")
+	}
+	return nil
+}
+
+func TestContextHooks(t *testing.T) {
+	ctx := context.WithValue(context.Background(), testCtxKey{}, "from context")
+	res, err := MarshalContext(ctx, &testCtxHooks{ID: 1}, Scope{})
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"hooks","attributes":{"name":"from context"}}`, string(res))
+
+	b := []byte(`{"data":{"type":"hooks","attributes":{"name":"n"}}}`)
+	_, err = UnmarshalWithOptions(b, &testCtxHooks{}, UnmarshalOptions{Context: context.Background(), Operation: OperationCreate})
+	assertNil(t, err)
+	_, err = UnmarshalWithOptions(b, &testCtxHooks{}, UnmarshalOptions{Context: context.WithValue(context.Background(), testCtxKey{}, "deny"), Operation: OperationCreate})
+	assertEqual(t, "denied", err.Error())
+}
+
+func TestContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := MarshalContext(ctx, []testPost{{ID: 1}, {ID: 2}}, Scope{})
+	assertEqual(t, true, errors.Is(err, context.Canceled))
+
+	err = UnmarshalContext(ctx, []byte(`{"data":{"type":"posts"}}`), &testPost{}, Scope{})
+	assertEqual(t, true, errors.Is(err, context.Canceled))
+}
+
+type testLifecycle struct {
+	ID       uint64   `jsonapi:"id,lifecycle"`
+	Email    string   `jsonapi:"attr,email" transform:"trim,lower" validate:"email"`
+	Code     testCode `jsonapi:"attr,code" transform:"upper"`
+	Locked   bool     `jsonapi:"attr,locked,readonly"`
+	previous string
+}
+
+type testCode string
+
+func (l *testLifecycle) BeforeUnmarshalJSONAPI() error {
+	if l.Locked {
+		return ErrorForbidden("locked")
+	}
+	l.previous = l.Email
+	return nil
+}
+
+func (l *testLifecycle) AfterMarshalJSONAPI(b []byte) ([]byte, error) {
+	return append(b[:len(b)-1], `,"meta":{"v":1}}`...), nil
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	res, err := Marshal([]testLifecycle{{ID: 1, Email: "a@b.c"}})
+	assertNil(t, err)
+	assertEqual(t, `[{"id":"1","type":"lifecycle","attributes":{"email":"a@b.c","code":"","locked":false},"meta":{"v":1}}]`, string(res))
+
+	b := []byte(`{"data":{"type":"lifecycle","attributes":{"email":"  John@Example.COM ","code":"ab"}}}`)
+	l := testLifecycle{Email: "old@b.c"}
+	changes, err := UnmarshalWithChanges(b, &l)
+	assertNil(t, err)
+	assertEqual(t, "john@example.com", l.Email)
+	assertEqual(t, testCode("AB"), l.Code)
+	assertEqual(t, "old@b.c", l.previous)
+	assertEqual(t, "john@example.com", changes.Find("email").New)
+
+	l = testLifecycle{Email: "old@b.c", Locked: true}
+	err = Unmarshal(b, &l)
+	assertEqual(t, "locked", err.Error())
+	assertEqual(t, "old@b.c", l.Email)
+}
+
+func TestRegisterTransform(t *testing.T) {
+	RegisterTransform("double", func(v interface{}) (interface{}, error) {
+		return v.(int) * 2, nil
+	})
+	s := struct {
+		ID    uint64 `jsonapi:"id,doubles"`
+		Count int    `jsonapi:"attr,count" transform:"double"`
+	}{}
+	assertNil(t, Unmarshal([]byte(`{"data":{"type":"doubles","attributes":{"count":2}}}`), &s))
+	assertEqual(t, 4, s.Count)
+}
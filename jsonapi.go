@@ -2,16 +2,21 @@ package jsonapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"unicode"
 )
 
-var types = typesCache{m: make(map[reflect.Type]*fields)}
+var types = newTypesCache()
 
-// Marshaler interface example
+// Marshaler interface writes raw resource object which must be valid JSON object.
+// Use ResourceMarshaler to have resource object scoped and linked like tagged structures.
 // 	func (p *Post) MarshalJSONAPI() ([]byte, error) {
 // 		return []byte(`{"custom":"return"}`), nil
 // 	}
@@ -22,13 +27,22 @@ type Marshaler interface {
 // BeforeMarshaler interface
 // 	func (p *Post) BeforeMarshalJSONAPI() error {
 // 		p.SelfLink = fmt.Sprintf("/api/posts/%d", p.ID)
-// 		p.Comments.Related = fmt.Sprintf("/api/posts/%d/comments", p.ID)
+// 		p.Comments.Links.Related = jsonapi.Link{Href: fmt.Sprintf("/api/posts/%d/comments", p.ID)}
 // 		return nil
 // 	}
 type BeforeMarshaler interface {
 	BeforeMarshalJSONAPI() error
 }
 
+// BeforeMarshalerContext interface receives context passed to MarshalContext
+// 	func (p *Post) BeforeMarshalJSONAPI(ctx context.Context) error {
+// 		p.Liked = likes.FromContext(ctx).Has(p.ID)
+// 		return nil
+// 	}
+type BeforeMarshalerContext interface {
+	BeforeMarshalJSONAPI(ctx context.Context) error
+}
+
 // Unmarshaler interface
 type Unmarshaler interface {
 	UnmarshalJSONAPI([]byte) error
@@ -39,6 +53,28 @@ type AfterUnmarshaler interface {
 	AfterUnmarshalJSONAPI() error
 }
 
+// AfterUnmarshalerContext interface receives context passed to UnmarshalContext
+type AfterUnmarshalerContext interface {
+	AfterUnmarshalJSONAPI(ctx context.Context) error
+}
+
+// AttributeReader interface hides attributes and relationships from caller on marshal
+// 	func (u *User) CanReadAttribute(ctx context.Context, name string) bool {
+// 		return name != "email" || currentUserID(ctx) == u.ID
+// 	}
+type AttributeReader interface {
+	CanReadAttribute(ctx context.Context, name string) bool
+}
+
+// AttributeWriter interface rejects attributes and relationships submitted by caller
+// on unmarshal. Every rejected member is reported as 403 error with pointer.
+// 	func (u *User) CanWriteAttribute(ctx context.Context, name string) bool {
+// 		return name != "role" || isAdmin(ctx)
+// 	}
+type AttributeWriter interface {
+	CanWriteAttribute(ctx context.Context, name string) bool
+}
+
 type withType interface {
 	JSONType() string
 }
@@ -52,17 +88,21 @@ type withID interface {
 }
 
 var (
-	marshalerType        = reflect.TypeOf(new(Marshaler)).Elem()
-	beforeMarshalerType  = reflect.TypeOf(new(BeforeMarshaler)).Elem()
-	unmarshalerType      = reflect.TypeOf(new(Unmarshaler)).Elem()
-	afterUnmarshalerType = reflect.TypeOf(new(AfterUnmarshaler)).Elem()
-	jsonMarshallerType   = reflect.TypeOf(new(json.Marshaler)).Elem()
-	withTypeType         = reflect.TypeOf(new(withType)).Elem()
-	withIDType           = reflect.TypeOf(new(withID)).Elem()
-	stringerType         = reflect.TypeOf(new(stringer)).Elem()
+	marshalerType           = reflect.TypeOf(new(Marshaler)).Elem()
+	beforeMarshalerType     = reflect.TypeOf(new(BeforeMarshaler)).Elem()
+	beforeMarshalerCtxType  = reflect.TypeOf(new(BeforeMarshalerContext)).Elem()
+	unmarshalerType         = reflect.TypeOf(new(Unmarshaler)).Elem()
+	afterUnmarshalerType    = reflect.TypeOf(new(AfterUnmarshaler)).Elem()
+	afterUnmarshalerCtxType = reflect.TypeOf(new(AfterUnmarshalerContext)).Elem()
+	jsonMarshallerType      = reflect.TypeOf(new(json.Marshaler)).Elem()
+	withTypeType            = reflect.TypeOf(new(withType)).Elem()
+	withIDType              = reflect.TypeOf(new(withID)).Elem()
+	stringerType            = reflect.TypeOf(new(stringer)).Elem()
 )
 
 // MetaData struct
+//
+// Deprecated: use Meta or any other map or structure for Response.Meta
 type MetaData struct {
 	Total  int         `json:"total"`
 	Limit  int         `json:"limit"`
@@ -72,20 +112,40 @@ type MetaData struct {
 
 // Response structure for json api response
 type Response struct {
-	Data     interface{} `json:"data,omitempty"`
-	Included interface{} `json:"included,omitempty"`
-	Meta     *MetaData   `json:"meta,omitempty"`
-	Scope    string      `json:"-"`
+	Data     interface{}     `json:"data,omitempty"`
+	Included interface{}     `json:"included,omitempty"`
+	Links    map[string]Link `json:"links,omitempty"`
+	Meta     interface{}     `json:"meta,omitempty"`
+	Scope    string          `json:"-"`
+	Roles    *Scope          `json:"-"`
+	Context  context.Context `json:"-"`
+	Language string          `json:"-"`
 	Errors
 }
 
+// scope returns Roles if set or Scope otherwise
+func (r *Response) scope() Scope {
+	if r.Roles != nil {
+		return *r.Roles
+	}
+	return scopeOf(r.Scope)
+}
+
+// context returns Context if set or background context otherwise
+func (r *Response) context() context.Context {
+	if r.Context != nil {
+		return r.Context
+	}
+	return context.Background()
+}
+
 // MarshalJSON marshaller
 func (r *Response) MarshalJSON() ([]byte, error) {
 	var b bytes.Buffer
 	var data []byte
 	var err error
 	if r.Data != nil {
-		data, err = MarshalWithScope(r.Data, r.Scope)
+		data, err = marshalWithScope(r.context(), r.Data, r.scope())
 		if err != nil {
 			return b.Bytes(), err
 		}
@@ -96,7 +156,7 @@ func (r *Response) MarshalJSON() ([]byte, error) {
 		b.Write(data)
 	}
 	if r.Included != nil {
-		data, err = MarshalWithScope(r.Included, r.Scope)
+		data, err = marshalWithScope(r.context(), r.Included, r.scope())
 		if err != nil {
 			return b.Bytes(), err
 		}
@@ -106,7 +166,18 @@ func (r *Response) MarshalJSON() ([]byte, error) {
 		b.WriteString(`"included":`)
 		b.Write(data)
 	}
-	if r.Meta != nil {
+	if len(r.Links) > 0 {
+		data, err = json.Marshal(r.Links)
+		if err != nil {
+			return b.Bytes(), err
+		}
+		if b.Len() > 2 {
+			b.WriteByte(',')
+		}
+		b.WriteString(`"links":`)
+		b.Write(data)
+	}
+	if r.Meta != nil && !isEmptyValue(reflect.ValueOf(r.Meta)) {
 		data, err = json.Marshal(r.Meta)
 		if err != nil {
 			return b.Bytes(), err
@@ -118,7 +189,11 @@ func (r *Response) MarshalJSON() ([]byte, error) {
 		b.Write(data)
 	}
 	if r.HasErrors() {
-		data, err = json.Marshal(r.Errors.Errors)
+		errs := r.Errors
+		if r.Language != "" {
+			errs = errs.Localize(ParseAcceptLanguage(r.Language)...)
+		}
+		data, err = json.Marshal(errs.Errors)
 		if err != nil {
 			return b.Bytes(), err
 		}
@@ -132,51 +207,53 @@ func (r *Response) MarshalJSON() ([]byte, error) {
 	return b.Bytes(), nil
 }
 
-// StatusCode returns first error status code or success
+// StatusCode returns success if there are no errors, status of errors if all errors
+// share the same status or the most generally applicable status otherwise:
+// 400 for 4xx errors and 500 if any of errors is 5xx or has no valid status
 func (r Errors) StatusCode() int {
-	if r.HasErrors() {
-		return strToInt(r.Errors[0].Status)
+	if !r.HasErrors() {
+		return 200
 	}
-	return 200
-}
-
-// Links structure
-type Links struct {
-	Self    string `json:"self,omitempty"`
-	Related string `json:"related,omitempty"`
+	code := 0
+	for k := range r.Errors {
+		c := r.Errors[k].StatusCode()
+		switch {
+		case c < 400:
+			return 500
+		case code == 0 || code == c:
+			code = c
+		case c >= 500 || code >= 500:
+			code = 500
+		default:
+			code = 400
+		}
+	}
+	return code
 }
 
 // Relation structure
 type Relation struct {
 	Links Links
 	Data  interface{}
+	Meta  interface{}
 }
 
 // MarshalJSON marshaller
 func (r Relation) MarshalJSON() ([]byte, error) {
 	var buf bytes.Buffer
 	buf.WriteByte('{')
-	if r.Links.Self != "" || r.Links.Related != "" {
-		buf.WriteString(`"links":{`)
-		if r.Links.Self != "" {
-			buf.WriteString(`"self":"`)
-			buf.WriteString(r.Links.Self)
-			buf.WriteByte('"')
-			if r.Links.Related != "" {
-				buf.WriteByte(',')
-			}
-		}
-		if r.Links.Related != "" {
-			buf.WriteString(`"related":"`)
-			buf.WriteString(r.Links.Related)
-			buf.WriteByte('"')
-		}
-		buf.WriteByte('}')
-		if r.Data != nil {
-			buf.WriteByte(',')
+	if !r.Links.Empty() {
+		buf.WriteString(`"links":`)
+		b, err := r.Links.MarshalJSON()
+		if err != nil {
+			return []byte{}, err
 		}
+		buf.Write(b)
 	}
 	if r.Data != nil {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
 		buf.WriteString(`"data":`)
 		b, err := json.Marshal(r.Data)
 		if err != nil {
@@ -184,6 +261,17 @@ func (r Relation) MarshalJSON() ([]byte, error) {
 		}
 		buf.Write(b)
 	}
+	if r.Meta != nil {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"meta":`)
+		b, err := json.Marshal(r.Meta)
+		if err != nil {
+			return []byte{}, err
+		}
+		buf.Write(b)
+	}
 	buf.WriteByte('}')
 	return buf.Bytes(), nil
 }
@@ -194,29 +282,33 @@ type fields struct {
 	attrs []field
 	links []field
 	rels  []field
+	meta  []field
+	err   error
+	// afterMarshal is true if pointer to structure implements AfterMarshaler
+	afterMarshal bool
 }
 
 func (f fields) api() bool {
 	return len(f.attrs) > 0
 }
 
-func (f *fields) checkID(el reflect.Value) {
+// checkID sets id and type of structure without id tag.
+// JSONID and JSONType are called on zero value so they must depend on type only.
+func (f *fields) checkID(t reflect.Type) {
 	if len(f.id) > 0 {
 		return
 	}
 
 	id := "ID"
 
-	t := el.Type()
-
 	if t.Implements(withIDType) {
-		id = el.Interface().(withID).JSONID()
+		id = reflect.Zero(t).Interface().(withID).JSONID()
 	}
 
 	if t.Implements(withTypeType) {
-		f.stype = el.Interface().(withType).JSONType()
+		f.stype = reflect.Zero(t).Interface().(withType).JSONType()
 	} else {
-		f.stype = stringTransform(t.Name(), "-")
+		f.stype = currentNaming().typeName(t.Name())
 	}
 
 	if id != "" {
@@ -228,45 +320,176 @@ func (f *fields) checkID(el reflect.Value) {
 }
 
 type field struct {
-	idx       []int
-	name      string
-	scopes    []string
-	readonly  bool
-	quote     bool
-	link      bool
-	skipEmpty bool
+	idx        []int
+	name       string
+	scope      fieldScope
+	readonly   bool
+	quote      bool
+	quoted     reflect.Type
+	link       bool
+	skipEmpty  bool
+	rules      []rule
+	transforms []TransformFunc
+	codec      *Codec
 }
 
-func (f field) inScope(s string) bool {
-	if len(f.scopes) == 0 || s == "" {
-		return true
+func (f field) allowed(a access, s Scope) bool {
+	return f.scope.allowed(a, s)
+}
+
+// typesCache is copy-on-write cache of structure metadata. Reads are lock free,
+// metadata of every type is built once under lock.
+type typesCache struct {
+	mu sync.Mutex
+	m  atomic.Value // map[reflect.Type]*fields
+}
+
+func newTypesCache() *typesCache {
+	c := &typesCache{}
+	c.m.Store(map[reflect.Type]*fields{})
+	return c
+}
+
+// reset drops cached metadata
+func (s *typesCache) reset() {
+	s.mu.Lock()
+	s.m.Store(map[reflect.Type]*fields{})
+	s.mu.Unlock()
+}
+
+// get returns metadata of structure type
+func (s *typesCache) get(t reflect.Type) *fields {
+	if f := s.m.Load().(map[reflect.Type]*fields)[t]; f != nil {
+		return f
 	}
-	for _, v := range f.scopes {
-		if v == s {
-			return true
-		}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur := s.m.Load().(map[reflect.Type]*fields)
+	if f := cur[t]; f != nil {
+		return f
+	}
+
+	f := buildFields(t)
+	m := make(map[reflect.Type]*fields, len(cur)+1)
+	for k, v := range cur {
+		m[k] = v
+	}
+	m[t] = f
+	s.m.Store(m)
+
+	if f.err != nil && development() {
+		panic(f.err.Error())
 	}
-	return false
+	return f
 }
 
-type typesCache struct {
+var developmentMode = flagStore{}
+
+type flagStore struct {
 	sync.RWMutex
-	m map[reflect.Type]*fields
+	on bool
 }
 
-func (s *typesCache) get(el reflect.Value) *fields {
-	s.RLock()
-	t := el.Type()
-	f := types.m[t]
-	s.RUnlock()
+// SetDevelopment turns development mode on or off. In development mode invalid
+// type metadata such as invalid member names panics when type is first used,
+// otherwise marshal and unmarshal of the type return error.
+func SetDevelopment(on bool) {
+	developmentMode.Lock()
+	developmentMode.on = on
+	developmentMode.Unlock()
+}
 
-	if f != nil {
-		return f
+func development() bool {
+	developmentMode.RLock()
+	on := developmentMode.on
+	developmentMode.RUnlock()
+	return on
+}
+
+// Prepare builds and validates metadata of resource structures, nested attribute
+// structures and structures of relationships. Prepare is meant to be called at startup
+// so invalid tags are reported early and first requests don't pay for building metadata.
+// Invalid tags are returned as error, they panic only in development mode.
+// 	if err := jsonapi.Prepare(&Post{}, &Comment{}); err != nil {
+// 		log.Fatal(err)
+// 	}
+func Prepare(items ...interface{}) error {
+	seen := map[reflect.Type]bool{}
+	for _, i := range items {
+		t := elemType(reflect.TypeOf(i))
+		if t == nil || t.Kind() != reflect.Struct {
+			return fmt.Errorf("jsonapi: can't prepare %T, struct expected", i)
+		}
+		if err := prepare(t, seen); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	s.Lock()
+func prepare(t reflect.Type, seen map[reflect.Type]bool) error {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	f := types.get(t)
+	if f.err != nil {
+		return f.err
+	}
+	for _, attr := range f.attrs {
+		if err := prepareNested(t.FieldByIndex(attr.idx).Type, seen); err != nil {
+			return err
+		}
+	}
+	for _, rel := range f.rels {
+		rt := elemType(t.FieldByIndex(rel.idx).Type)
+		if rt.Kind() != reflect.Struct || rt == relationType {
+			continue
+		}
+		if err := prepare(rt, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	f = &fields{}
+// prepareNested builds and validates rules of nested structures of attribute type
+func prepareNested(t reflect.Type, seen map[reflect.Type]bool) error {
+	t = elemType(t)
+	if t.Kind() != reflect.Struct || t == timeType || seen[t] {
+		return nil
+	}
+	seen[t] = true
+	nt := nestedFields(t)
+	if nt.err != nil {
+		return nt.err
+	}
+	for _, fd := range nt.fields {
+		if err := prepareNested(t.FieldByIndex(fd.idx).Type, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// elemType returns type of pointer, slice or array elements
+func elemType(t reflect.Type) reflect.Type {
+	for t != nil {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+	return t
+}
+
+// buildFields builds metadata of structure type from tags
+func buildFields(t reflect.Type) *fields {
+	f := &fields{}
 
 	for _, idx := range typeFields(t, []int{}) {
 		fd := t.FieldByIndex(idx)
@@ -283,63 +506,157 @@ func (s *typesCache) get(el reflect.Value) *fields {
 				f.stype = keys[1]
 			}
 		case "attr":
-			fld := field{idx: idx, name: fd.Name}
-			if len(keys) > 1 && validKey(keys[1]) {
-				fld.name = keys[1]
-			}
-			if len(keys) > 2 {
-				for _, v := range keys[2:] {
-					switch v {
-					case "readonly":
-						fld.readonly = true
-					case "string":
-						fld.quote = true
-					case "omitempty":
-						fld.skipEmpty = true
-					}
-				}
-			}
-			if scope := fd.Tag.Get("scope"); scope != "" {
-				fld.scopes = strings.Split(scope, ",")
-			}
+			fld, err := newField(idx, fd, keys)
+			f.fail(t, fd, err)
 			f.attrs = append(f.attrs, fld)
+		case "meta":
+			fld, err := newField(idx, fd, keys)
+			f.fail(t, fd, err)
+			f.meta = append(f.meta, fld)
 		case "link":
-			name := fd.Name
-			if len(keys) > 1 && validKey(keys[1]) {
-				name = keys[1]
-			}
-			f.links = append(f.links, field{idx: idx, name: name})
+			f.links = append(f.links, field{idx: idx, name: tagName(keys, fd.Name)})
 		case "rel":
-			name := fd.Name
-			if len(keys) > 1 && validKey(keys[1]) {
-				name = keys[1]
-			}
-			f.rels = append(f.rels, field{idx: idx, name: name})
+			name := tagName(keys, currentNaming().member(fd.Name))
+			scope, err := parseScope(fd.Tag.Get("scope"))
+			f.fail(t, fd, err)
+			f.rels = append(f.rels, field{idx: idx, name: name, scope: scope})
 		}
 	}
 
-	f.checkID(el)
-	s.m[t] = f
+	f.checkID(t)
+	f.afterMarshal = reflect.PtrTo(t).Implements(afterMarshalerType)
+	if f.err == nil {
+		f.err = f.validate(t)
+	}
+	return f
+}
 
-	s.Unlock()
+// fail records first error of field tags
+func (f *fields) fail(t reflect.Type, fd reflect.StructField, err error) {
+	if err != nil && f.err == nil {
+		f.err = fmt.Errorf("jsonapi: invalid tag of %s.%s: %v", t, fd.Name, err)
+	}
+}
 
-	return f
+// newField creates field from tag keys and options
+func newField(idx []int, fd reflect.StructField, keys []string) (field, error) {
+	fld := field{idx: idx, name: tagName(keys, currentNaming().member(fd.Name))}
+	format := ""
+	if len(keys) > 2 {
+		for _, v := range keys[2:] {
+			switch {
+			case v == "readonly":
+				fld.readonly = true
+			case v == "string":
+				fld.quote = true
+			case v == "omitempty":
+				fld.skipEmpty = true
+			case strings.HasPrefix(v, "format="):
+				format = v[len("format="):]
+			}
+		}
+	}
+	codec, ok := codecs.lookup(format, fd.Type)
+	if !ok {
+		return fld, fmt.Errorf("unknown format '%s'", format)
+	}
+	if codec != nil && codec.Type != nil {
+		ft := fd.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft != codec.Type {
+			return fld, fmt.Errorf("format '%s' requires %s, not %s", format, codec.Type, fd.Type)
+		}
+	}
+	fld.codec = codec
+	switch {
+	case fld.quote && codec == nil:
+		fld.quoted = quotedType(fd.Type)
+		fld.quote = fld.quoted != nil
+	case fld.quote:
+		// output of codecs encoding strings is not quoted again
+		fld.quote = codec.Schema["type"] != "string"
+	}
+	var err error
+	if fld.rules, err = parseRules(fd); err != nil {
+		return fld, err
+	}
+	if fld.transforms, err = parseTransforms(fd); err != nil {
+		return fld, err
+	}
+	fld.scope, err = parseScope(fd.Tag.Get("scope"))
+	return fld, err
 }
 
+// tagName returns member name from tag keys or def if name is not set
+func tagName(keys []string, def string) string {
+	if len(keys) > 1 && keys[1] != "" {
+		return keys[1]
+	}
+	return def
+}
+
+// validKey returns true if s is valid member name
 func validKey(s string) bool {
-	if s == "" {
-		return false
+	return validMember(s) == nil
+}
+
+// validMember returns error describing why name is not valid member name.
+// Member names contain letters, digits and non ASCII characters,
+// hyphen, low line and space are allowed except at start and end.
+// Names starting with @ are reserved for extension members.
+func validMember(name string) error {
+	if name == "" {
+		return errors.New("name is empty")
+	}
+	if name[0] == '@' {
+		return errors.New("names starting with @ are reserved for extension members")
 	}
-	for _, c := range s {
+	for i, c := range name {
 		switch {
-		case strings.ContainsRune("!#$%&()*+-./:<=>?@[]^_{|}~ ", c):
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c >= 0x80:
+		case c == '-' || c == '_' || c == ' ':
+			if i == 0 || i == len(name)-1 {
+				return fmt.Errorf("%q is not allowed at start or end", c)
+			}
 		default:
-			if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
-				return false
+			return fmt.Errorf("%q is not allowed", c)
+		}
+	}
+	return nil
+}
+
+// validate returns error for invalid type or member names,
+// attributes and relationships named id or type and duplicate names
+func (f *fields) validate(t reflect.Type) error {
+	if f.api() {
+		if err := validMember(f.stype); err != nil {
+			return fmt.Errorf("jsonapi: invalid type %q of %s: %v", f.stype, t, err)
+		}
+	}
+	seen := map[string]bool{}
+	for _, group := range [][]field{f.attrs, f.rels, f.meta, f.links} {
+		for _, fd := range group {
+			name := t.FieldByIndex(fd.idx).Name
+			if err := validMember(fd.name); err != nil {
+				return fmt.Errorf("jsonapi: invalid member name %q of %s.%s: %v", fd.name, t, name, err)
+			}
+		}
+	}
+	for _, group := range [][]field{f.attrs, f.rels} {
+		for _, fd := range group {
+			name := t.FieldByIndex(fd.idx).Name
+			switch {
+			case fd.name == "id" || fd.name == "type":
+				return fmt.Errorf("jsonapi: member name %q of %s.%s is reserved", fd.name, t, name)
+			case seen[fd.name]:
+				return fmt.Errorf("jsonapi: duplicate member name %q of %s.%s", fd.name, t, name)
 			}
+			seen[fd.name] = true
 		}
 	}
-	return true
+	return nil
 }
 
 func interfacePtr(i interface{}) reflect.Value {
@@ -0,0 +1,254 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Link is link string or link object. Link with href only is marshalled as string.
+// 	SelfLink jsonapi.Link `jsonapi:"link,self"`
+// 	p.SelfLink = jsonapi.Link{Href: "/api/posts/1", Meta: map[string]int{"version": 2}}
+type Link struct {
+	Href        string
+	Rel         string
+	DescribedBy *Link
+	Title       string
+	Type        string
+	HrefLang    []string
+	Meta        interface{}
+}
+
+type linkObject struct {
+	Href        string          `json:"href"`
+	Rel         string          `json:"rel,omitempty"`
+	DescribedBy *Link           `json:"describedby,omitempty"`
+	Title       string          `json:"title,omitempty"`
+	Type        string          `json:"type,omitempty"`
+	HrefLang    json.RawMessage `json:"hreflang,omitempty"`
+	Meta        interface{}     `json:"meta,omitempty"`
+}
+
+// Empty returns true if link has no href and no members
+func (l Link) Empty() bool {
+	return l.Href == "" && l.hrefOnly()
+}
+
+func (l Link) hrefOnly() bool {
+	return l.Rel == "" && l.DescribedBy == nil && l.Title == "" && l.Type == "" && len(l.HrefLang) == 0 && l.Meta == nil
+}
+
+// String returns link href
+func (l Link) String() string {
+	return l.Href
+}
+
+// MarshalJSON marshaller
+func (l Link) MarshalJSON() ([]byte, error) {
+	if l.Empty() {
+		return []byte("null"), nil
+	}
+	if l.hrefOnly() {
+		return json.Marshal(l.Href)
+	}
+
+	o := linkObject{
+		Href:        l.Href,
+		Rel:         l.Rel,
+		DescribedBy: l.DescribedBy,
+		Title:       l.Title,
+		Type:        l.Type,
+		Meta:        l.Meta,
+	}
+	var err error
+	switch len(l.HrefLang) {
+	case 0:
+	case 1:
+		o.HrefLang, err = json.Marshal(l.HrefLang[0])
+	default:
+		o.HrefLang, err = json.Marshal(l.HrefLang)
+	}
+	if err != nil {
+		return []byte{}, err
+	}
+	return json.Marshal(o)
+}
+
+// UnmarshalJSON unmarshaller
+func (l *Link) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	switch {
+	case bytes.Equal(b, []byte("null")):
+		*l = Link{}
+		return nil
+	case len(b) > 0 && b[0] == '"':
+		*l = Link{}
+		return json.Unmarshal(b, &l.Href)
+	}
+
+	o := linkObject{}
+	if err := json.Unmarshal(b, &o); err != nil {
+		return err
+	}
+	*l = Link{
+		Href:        o.Href,
+		Rel:         o.Rel,
+		DescribedBy: o.DescribedBy,
+		Title:       o.Title,
+		Type:        o.Type,
+		Meta:        o.Meta,
+	}
+	if len(o.HrefLang) > 0 {
+		if o.HrefLang[0] == '[' {
+			return json.Unmarshal(o.HrefLang, &l.HrefLang)
+		}
+		l.HrefLang = make([]string, 1)
+		return json.Unmarshal(o.HrefLang, &l.HrefLang[0])
+	}
+	return nil
+}
+
+// Links structure
+type Links struct {
+	Self    Link `json:"self,omitempty"`
+	Related Link `json:"related,omitempty"`
+}
+
+// Empty returns true if there are no links
+func (l Links) Empty() bool {
+	return l.Self.Empty() && l.Related.Empty()
+}
+
+// MarshalJSON marshaller
+func (l Links) MarshalJSON() ([]byte, error) {
+	return marshalLinks([]string{"self", "related"}, l.Self, l.Related)
+}
+
+// marshalLinks writes links object skipping empty links
+func marshalLinks(names []string, links ...Link) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := range links {
+		if links[i].Empty() {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('"')
+		buf.WriteString(names[i])
+		buf.WriteString(`":`)
+		b, err := links[i].MarshalJSON()
+		if err != nil {
+			return []byte{}, err
+		}
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+var linker = linkerStore{}
+
+type linkerStore struct {
+	sync.RWMutex
+	l *Linker
+}
+
+// SetLinker enables automatic links generation for marshalled resources.
+// Linker must not be changed after it was set. Pass nil to turn generation off.
+// 	jsonapi.SetLinker(&jsonapi.Linker{
+// 		BaseURL: "https://example.com/api",
+// 		Routes:  map[string]jsonapi.Route{"people": {Self: "/users/{id}"}},
+// 	})
+func SetLinker(l *Linker) {
+	linker.Lock()
+	linker.l = l
+	linker.Unlock()
+}
+
+func currentLinker() *Linker {
+	linker.RLock()
+	l := linker.l
+	linker.RUnlock()
+	return l
+}
+
+// Default link templates
+const (
+	DefaultSelfRoute         = "/{type}/{id}"
+	DefaultRelationSelfRoute = "/{type}/{id}/relationships/{rel}"
+	DefaultRelatedRoute      = "/{type}/{id}/{rel}"
+)
+
+// Linker generates resource self links and relationship self and related links.
+// Templates may contain {type}, {id} and {rel} placeholders and are appended to BaseURL.
+// Empty templates fall back to Default*Route constants.
+// Links set explicitly on resource fields or Relation are never overwritten.
+// Links are not generated for resources with empty or zero id.
+type Linker struct {
+	BaseURL      string
+	Self         string
+	RelationSelf string
+	Related      string
+	Routes       map[string]Route
+}
+
+// Route overrides Linker templates for resource type
+type Route struct {
+	Self         string
+	RelationSelf string
+	Related      string
+	Disabled     bool
+}
+
+func (l *Linker) route(stype string) (Route, bool) {
+	if l == nil {
+		return Route{}, false
+	}
+	r := l.Routes[stype]
+	if r.Disabled {
+		return r, false
+	}
+	r.Self = firstString(r.Self, l.Self, DefaultSelfRoute)
+	r.RelationSelf = firstString(r.RelationSelf, l.RelationSelf, DefaultRelationSelfRoute)
+	r.Related = firstString(r.Related, l.Related, DefaultRelatedRoute)
+	return r, true
+}
+
+func (l *Linker) expand(tpl, stype, id, rel string) string {
+	path := strings.NewReplacer("{type}", stype, "{id}", url.PathEscape(id), "{rel}", rel).Replace(tpl)
+	return strings.TrimSuffix(l.BaseURL, "/") + path
+}
+
+// resourceLink returns resource self link or empty string if generation is off
+func (l *Linker) resourceLink(stype, id string) string {
+	r, ok := l.route(stype)
+	if !ok || id == "" {
+		return ""
+	}
+	return l.expand(r.Self, stype, id, "")
+}
+
+// relationLinks returns relationship self and related links
+func (l *Linker) relationLinks(stype, id, rel string) Links {
+	r, ok := l.route(stype)
+	if !ok || id == "" {
+		return Links{}
+	}
+	return Links{
+		Self:    Link{Href: l.expand(r.RelationSelf, stype, id, rel)},
+		Related: Link{Href: l.expand(r.Related, stype, id, rel)},
+	}
+}
+
+func firstString(items ...string) string {
+	for _, s := range items {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
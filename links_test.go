@@ -0,0 +1,132 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLinkMarshal(t *testing.T) {
+	res, err := json.Marshal(Link{Href: `/posts?q="a"`})
+	assertNil(t, err)
+	assertEqual(t, `"/posts?q=\"a\""`, string(res))
+
+	res, err = json.Marshal(Link{})
+	assertNil(t, err)
+	assertEqual(t, `null`, string(res))
+
+	l := Link{
+		Href:        "/posts/1",
+		Rel:         "canonical",
+		DescribedBy: &Link{Href: "/schemas/posts"},
+		Title:       "Post",
+		Type:        "application/vnd.api+json",
+		HrefLang:    []string{"en"},
+		Meta:        map[string]int{"version": 2},
+	}
+	want := `{"href":"/posts/1","rel":"canonical","describedby":"/schemas/posts","title":"Post","type":"application/vnd.api+json","hreflang":"en","meta":{"version":2}}`
+	res, err = json.Marshal(l)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	l.HrefLang = []string{"en", "de"}
+	res, err = json.Marshal(l)
+	assertNil(t, err)
+
+	l1 := Link{}
+	assertNil(t, json.Unmarshal(res, &l1))
+	assertEqual(t, "/posts/1", l1.Href)
+	assertEqual(t, []string{"en", "de"}, l1.HrefLang)
+	assertEqual(t, "/schemas/posts", l1.DescribedBy.Href)
+	assertEqual(t, map[string]interface{}{"version": float64(2)}, l1.Meta)
+
+	assertNil(t, json.Unmarshal([]byte(`"/posts/2"`), &l1))
+	assertEqual(t, Link{Href: "/posts/2"}, l1)
+}
+
+func TestLinksMarshal(t *testing.T) {
+	r := Relation{Links: Links{Related: Link{Href: `/a"b`}}}
+	res, err := json.Marshal(r)
+	assertNil(t, err)
+	assertEqual(t, `{"links":{"related":"/a\"b"}}`, string(res))
+
+	r1 := Relation{}
+	assertNil(t, json.Unmarshal(res, &r1))
+	assertEqual(t, r, r1)
+
+	e := Error{Status: "404", Links: &ErrorLinks{About: Link{Href: "/docs/404"}}}
+	res, err = json.Marshal(e)
+	assertNil(t, err)
+	assertEqual(t, `{"links":{"about":"/docs/404"},"status":"404"}`, string(res))
+
+	resp := Response{Links: map[string]Link{"self": {Href: "/posts"}}}
+	res, err = resp.MarshalJSON()
+	assertNil(t, err)
+	assertEqual(t, `{"links":{"self":"/posts"}}`, string(res))
+}
+
+func TestUnmarshalLinks(t *testing.T) {
+	s := struct {
+		ID   uint64   `jsonapi:"id,test-structs"`
+		Name string   `jsonapi:"attr,name"`
+		Self string   `jsonapi:"link,self"`
+		Alt  Link     `jsonapi:"link,alt"`
+		Rel1 Relation `jsonapi:"rel,rel1"`
+	}{}
+	req := `{"data":{"id":"1","type":"test-structs","attributes":{"name":"A"},"links":{"self":{"href":"/s/1"},"alt":{"href":"/a/1","title":"Alt"}},"relationships":{"rel1":{"links":{"related":"/s/1/rel1"}}}}}`
+
+	assertNil(t, Unmarshal([]byte(req), &s))
+	assertEqual(t, "/s/1", s.Self)
+	assertEqual(t, Link{Href: "/a/1", Title: "Alt"}, s.Alt)
+	assertEqual(t, "/s/1/rel1", s.Rel1.Links.Related.Href)
+}
+
+func TestLinker(t *testing.T) {
+	SetLinker(&Linker{
+		BaseURL: "http://example.com/api/",
+		Routes: map[string]Route{
+			"test-structs": {Self: "/structs/{id}"},
+			"photos":       {Disabled: true},
+		},
+	})
+	defer SetLinker(nil)
+
+	s := testRelations{ID: 100, Name: "A"}
+	s.Rel1.Links.Related = Link{Href: "/custom"}
+	want := `{"id":"100","type":"test-rels","attributes":{"name":"A"},"links":{"self":"http://example.com/api/test-rels/100"},"relationships":{"rel1":{"links":{"self":"http://example.com/api/test-rels/100/relationships/rel1","related":"/custom"}}}}`
+	res, err := Marshal(&s)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	c := testComment{ID: 1, Body: "b", Commentable: &testPhoto{ID: "a 1"}}
+	want = `{"id":"1","type":"comments","attributes":{"body":"b"},"links":{"self":"http://example.com/api/comments/1"},"relationships":{` +
+		`"commentable":{"links":{"self":"http://example.com/api/comments/1/relationships/commentable","related":"http://example.com/api/comments/1/commentable"},"data":{"type":"photos","id":"a 1"}},` +
+		`"attachments":{"links":{"self":"http://example.com/api/comments/1/relationships/attachments","related":"http://example.com/api/comments/1/attachments"},"data":[]},` +
+		`"author":{"links":{"self":"http://example.com/api/comments/1/relationships/author","related":"http://example.com/api/comments/1/author"},"data":null}}}`
+	res, err = Marshal(&c)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	l := testLinkStruct{ID: 5, Name: "J"}
+	want = `{"id":"5","type":"test-structs","attributes":{"name":"J"},"links":{"self":"http://example.com/api/structs/5"}}`
+	res, err = Marshal(&l)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	l.Self = "/explicit"
+	want = `{"id":"5","type":"test-structs","attributes":{"name":"J"},"links":{"self":"/explicit"}}`
+	res, err = Marshal(&l)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	c = testComment{Body: "new"}
+	want = `{"id":"0","type":"comments","attributes":{"body":"new"},"relationships":{"commentable":{"data":null},"attachments":{"data":[]},"author":{"data":null}}}`
+	res, err = Marshal(&c)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	p := testPhoto{ID: "x", URL: "u"}
+	want = `{"id":"x","type":"photos","attributes":{"url":"u"}}`
+	res, err = Marshal(&p)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+}
@@ -0,0 +1,89 @@
+package jsonapi
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+var mappers = errorMappers{}
+
+type errorMapper struct {
+	match   func(error) (error, bool)
+	convert func(error) Error
+}
+
+type errorMappers struct {
+	sync.RWMutex
+	m []errorMapper
+}
+
+func (s *errorMappers) add(m errorMapper) {
+	s.Lock()
+	s.m = append(s.m, m)
+	s.Unlock()
+}
+
+// convert returns Error from the first matching mapper
+func (s *errorMappers) convert(err error) (Error, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	for _, m := range s.m {
+		if v, ok := m.match(err); ok {
+			return m.convert(v), true
+		}
+	}
+	return Error{}, false
+}
+
+// RegisterError registers Error returned for errors matching target with errors.Is
+// 	jsonapi.RegisterError(sql.ErrNoRows, jsonapi.ErrorRecordNotFound)
+// 	jsonapi.RegisterError(context.DeadlineExceeded, jsonapi.Error{Status: "504", Title: "Gateway Timeout"})
+func RegisterError(target error, e Error) {
+	mappers.add(errorMapper{
+		match: func(err error) (error, bool) {
+			return err, errors.Is(err, target)
+		},
+		convert: func(error) Error {
+			return e
+		},
+	})
+}
+
+// RegisterErrorType registers conversion for errors of the same type as sample found with errors.As.
+// Matched error is passed to conversion function.
+// 	jsonapi.RegisterErrorType(&NotAllowedError{}, func(err error) jsonapi.Error {
+// 		return jsonapi.ErrorForbidden(err.(*NotAllowedError).Reason)
+// 	})
+func RegisterErrorType(sample error, fn func(error) Error) {
+	t := reflect.TypeOf(sample)
+	if t == nil {
+		panic("jsonapi: can't register error type of nil")
+	}
+	mappers.add(errorMapper{
+		match: func(err error) (error, bool) {
+			v := reflect.New(t)
+			if !errors.As(err, v.Interface()) {
+				return err, false
+			}
+			return v.Elem().Interface().(error), true
+		},
+		convert: fn,
+	})
+}
+
+// RegisterErrorFunc registers conversion for errors matching predicate
+// 	jsonapi.RegisterErrorFunc(os.IsTimeout, func(err error) jsonapi.Error {
+// 		return jsonapi.Error{Status: "504", Title: "Gateway Timeout"}
+// 	})
+func RegisterErrorFunc(match func(error) bool, fn func(error) Error) {
+	if match == nil || fn == nil {
+		panic("jsonapi: can't register nil error mapper")
+	}
+	mappers.add(errorMapper{
+		match: func(err error) (error, bool) {
+			return err, match(err)
+		},
+		convert: fn,
+	})
+}
@@ -0,0 +1,77 @@
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testQuotaError struct {
+	Limit int
+}
+
+func (e *testQuotaError) Error() string {
+	return fmt.Sprintf("quota %d exceeded", e.Limit)
+}
+
+// resetMappers removes mappers registered by test when it finishes
+func resetMappers(t *testing.T) {
+	mappers.RLock()
+	n := len(mappers.m)
+	mappers.RUnlock()
+	t.Cleanup(func() {
+		mappers.Lock()
+		mappers.m = mappers.m[:n]
+		mappers.Unlock()
+	})
+}
+
+func TestErrorMappers(t *testing.T) {
+	resetMappers(t)
+	errMissing := errors.New("missing")
+	RegisterError(errMissing, ErrorRecordNotFound)
+	RegisterErrorType(&testQuotaError{}, func(err error) Error {
+		return Error{Status: "429", Title: "Too Many Requests", Detail: err.Error()}
+	})
+	RegisterErrorFunc(func(err error) bool {
+		return strings.HasPrefix(err.Error(), "bad input")
+	}, func(err error) Error {
+		return ErrorBadRequest(err.Error())
+	})
+
+	e := Errors{}
+	e.AddError(fmt.Errorf("find: %w", errMissing))
+	e.AddError(fmt.Errorf("call: %w", &testQuotaError{Limit: 10}))
+	e.AddError(errors.New("bad input: x"))
+	e.AddError(context.DeadlineExceeded)
+
+	assertEqual(t, "404", e.Errors[0].Status)
+	assertEqual(t, true, errors.Is(e.Errors[0], errMissing))
+	assertEqual(t, "quota 10 exceeded", e.Errors[1].Detail)
+	assertEqual(t, "400", e.Errors[2].Status)
+	assertEqual(t, "500", e.Errors[3].Status)
+}
+
+func TestErrorsStatusCode(t *testing.T) {
+	e := Errors{}
+	assertEqual(t, 200, e.StatusCode())
+
+	e.AddError(ErrorRecordNotFound)
+	e.AddError(ErrorPageNotFound)
+	assertEqual(t, 404, e.StatusCode())
+
+	e.AddError(ErrorInvalidAttribute("name", "required"))
+	assertEqual(t, 400, e.StatusCode())
+
+	e.AddError(Error{Status: "503"})
+	assertEqual(t, 500, e.StatusCode())
+
+	e = Errors{}
+	e.AddError(Error{Status: "503"})
+	assertEqual(t, 503, e.StatusCode())
+
+	e.AddError(Error{Detail: "no status"})
+	assertEqual(t, 500, e.StatusCode())
+}
@@ -2,28 +2,45 @@ package jsonapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
 )
 
 var (
-	errMarshalInvalidData = errors.New("jsonapi: invalid data structure passed for marshalling")
+	errMarshalInvalidData     = errors.New("jsonapi: invalid data structure passed for marshalling")
+	errMarshalInvalidRelation = errors.New("jsonapi: relationship item must be a struct with id")
 )
 
 // MarshalWithScope item to json api format
 func MarshalWithScope(i interface{}, scope string) ([]byte, error) {
-	return marshalWithScope(i, scope)
+	return marshalWithScope(context.Background(), i, scopeOf(scope))
+}
+
+// MarshalScope item to json api format with fields readable by scope roles
+// 	b, err := jsonapi.MarshalScope(user, jsonapi.Scope{Roles: roles, Strict: true})
+func MarshalScope(i interface{}, scope Scope) ([]byte, error) {
+	return marshalWithScope(context.Background(), i, scope)
+}
+
+// MarshalContext item to json api format with fields readable by scope roles.
+// Context is passed to BeforeMarshalerContext and AttributeReader of every resource,
+// marshalling of slice stops with context error when context is done.
+// 	b, err := jsonapi.MarshalContext(r.Context(), user, jsonapi.Roles("owner"))
+func MarshalContext(ctx context.Context, i interface{}, scope Scope) ([]byte, error) {
+	return marshalWithScope(ctx, i, scope)
 }
 
 // Marshal item to json api format
 func Marshal(i interface{}) ([]byte, error) {
-	return marshalWithScope(i, "")
+	return marshalWithScope(context.Background(), i, Scope{})
 }
 
 // Marshal item to json api format
-func marshalWithScope(i interface{}, scope string) ([]byte, error) {
+func marshalWithScope(ctx context.Context, i interface{}, scope Scope) ([]byte, error) {
 	e := interfacePtr(i)
 	if !e.IsValid() {
 		return []byte{}, errMarshalInvalidData
@@ -34,12 +51,15 @@ func marshalWithScope(i interface{}, scope string) ([]byte, error) {
 		e1 = e.Elem()
 	}
 
-	c := &encoder{}
+	c := &encoder{ctx: ctx, linker: currentLinker()}
 	switch e1.Type().Kind() {
 	case reflect.Slice, reflect.Array:
 		c.WriteByte('[')
 		iLen := e1.Len()
 		for i := 0; i < iLen; i++ {
+			if err := ctx.Err(); err != nil {
+				return []byte{}, err
+			}
 			if err := c.marshal(valuePtr(e1.Index(i)), scope); err != nil {
 				return []byte{}, err
 			}
@@ -60,13 +80,19 @@ func marshalWithScope(i interface{}, scope string) ([]byte, error) {
 type encoder struct {
 	bytes.Buffer
 	buffer [64]byte
+	ctx    context.Context
+	linker *Linker
 }
 
-func (e *encoder) marshal(el reflect.Value, scope string) error {
+func (e *encoder) marshal(el reflect.Value, scope Scope) error {
 	t := el.Type()
-	if t.Implements(beforeMarshalerType) {
-		m := el.Interface().(BeforeMarshaler)
-		if err := m.BeforeMarshalJSONAPI(); err != nil {
+	switch {
+	case t.Implements(beforeMarshalerType):
+		if err := el.Interface().(BeforeMarshaler).BeforeMarshalJSONAPI(); err != nil {
+			return err
+		}
+	case t.Implements(beforeMarshalerCtxType):
+		if err := el.Interface().(BeforeMarshalerContext).BeforeMarshalJSONAPI(e.ctx); err != nil {
 			return err
 		}
 	}
@@ -76,9 +102,27 @@ func (e *encoder) marshal(el reflect.Value, scope string) error {
 		if err != nil {
 			return err
 		}
+		if err := validResource(b); err != nil {
+			return err
+		}
 		e.Write(b)
 		return nil
 	}
+	if t.Implements(resourceMarshalerType) {
+		o, err := el.Interface().(ResourceMarshaler).MarshalJSONAPIResource()
+		if err != nil {
+			return err
+		}
+		reader, _ := el.Interface().(AttributeReader)
+		start := e.Len()
+		if err := e.marshalResource(o, scope, reader); err != nil {
+			return err
+		}
+		if t.Implements(afterMarshalerType) {
+			return e.afterMarshal(el.Interface().(AfterMarshaler), start)
+		}
+		return nil
+	}
 
 	if t.Kind() == reflect.Ptr {
 		el = el.Elem()
@@ -89,109 +133,215 @@ func (e *encoder) marshal(el reflect.Value, scope string) error {
 		return errMarshalInvalidData
 	}
 
-	f := types.get(el)
+	f := types.get(el.Type())
+	if f.err != nil {
+		return f.err
+	}
 	if !f.api() {
 		b, err := json.Marshal(el.Interface())
 		e.Write(b)
 		return err
 	}
+	reader, _ := valuePtr(el).Interface().(AttributeReader)
 
+	start := e.Len()
 	e.WriteByte('{')
 	e.WriteString(`"id":`)
-
-	id := el.FieldByIndex(f.id)
-	if id.Type().Implements(jsonMarshallerType) {
-		m := id.Interface().(json.Marshaler)
-		b, _ := m.MarshalJSON()
-		e.Write(b)
-	} else {
-		e.WriteByte('"')
-		switch id.Kind() {
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-			e.Write(strconv.AppendUint(e.buffer[:0], id.Uint(), 10))
-		case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
-			e.Write(strconv.AppendInt(e.buffer[:0], id.Int(), 10))
-		case reflect.String:
-			e.WriteString(id.String())
-		}
-		e.WriteByte('"')
-	}
+	e.writeID(el.FieldByIndex(f.id))
 	e.WriteString(`,"type":"`)
 	e.WriteString(f.stype)
 	if len(f.attrs) > 0 {
-		empty := true
 		e.WriteString(`","attributes":{`)
-		for k := range f.attrs {
-			ev := el.FieldByIndex(f.attrs[k].idx)
-			if f.attrs[k].skipEmpty && isEmptyValue(ev) {
+		if _, err := e.writeMembers(el, f.attrs, scope, reader); err != nil {
+			return err
+		}
+		e.WriteByte('}')
+	}
+	// links are not generated for new resources without id
+	id := ""
+	if idv := el.FieldByIndex(f.id); e.linker != nil && !isEmptyValue(idv) {
+		id = idString(idv)
+	}
+	self := e.linker.resourceLink(f.stype, id)
+	if len(f.links) > 0 || self != "" {
+		e.WriteString(`,"links":{`)
+		empty := true
+		for k := range f.links {
+			lv := el.FieldByIndex(f.links[k].idx)
+			if f.links[k].name == "self" && self != "" && emptyLink(lv) {
 				continue
 			}
-			if !f.attrs[k].inScope(scope) {
-				continue
+			if f.links[k].name == "self" {
+				self = ""
 			}
 			if !empty {
 				e.WriteByte(',')
 			}
+			empty = false
 			e.WriteByte('"')
-			e.WriteString(f.attrs[k].name)
+			e.WriteString(f.links[k].name)
 			e.WriteByte('"')
 			e.WriteByte(':')
-			b, err := json.Marshal(ev.Interface())
+			b, err := json.Marshal(lv.Interface())
 			if err != nil {
 				return err
 			}
-			if f.attrs[k].quote {
-				e.WriteByte('"')
-			}
 			e.Write(b)
-			if f.attrs[k].quote {
-				e.WriteByte('"')
-			}
-			empty = false
 		}
-		e.WriteByte('}')
-	}
-	if len(f.links) > 0 {
-		e.WriteString(`,"links":{`)
-		for k := range f.links {
-			if k > 0 {
+		if self != "" {
+			if !empty {
 				e.WriteByte(',')
 			}
-			e.WriteByte('"')
-			e.WriteString(f.links[k].name)
-			e.WriteByte('"')
-			e.WriteByte(':')
-			b, err := json.Marshal(el.FieldByIndex(f.links[k].idx).Interface())
-			if err != nil {
-				return err
-			}
+			e.WriteString(`"self":`)
+			b, _ := json.Marshal(self)
 			e.Write(b)
 		}
 		e.WriteByte('}')
 	}
 	if len(f.rels) > 0 {
+		n := e.Len()
 		e.WriteString(`,"relationships":{`)
+		empty := true
 		for k := range f.rels {
-			if k > 0 {
+			if !f.rels[k].allowed(accessRead, scope) || !e.canRead(reader, f.rels[k].name) {
+				continue
+			}
+			if !empty {
 				e.WriteByte(',')
 			}
+			empty = false
 			e.WriteByte('"')
 			e.WriteString(f.rels[k].name)
 			e.WriteByte('"')
 			e.WriteByte(':')
-			b, err := json.Marshal(el.FieldByIndex(f.rels[k].idx).Interface())
-			if err != nil {
+			links := e.linker.relationLinks(f.stype, id, f.rels[k].name)
+			if err := e.marshalRelation(el.FieldByIndex(f.rels[k].idx), links); err != nil {
 				return err
 			}
-			e.Write(b)
 		}
-		e.WriteByte('}')
+		if empty {
+			e.Truncate(n)
+		} else {
+			e.WriteByte('}')
+		}
+	}
+	if len(f.meta) > 0 {
+		n := e.Len()
+		e.WriteString(`,"meta":{`)
+		ok, err := e.writeMembers(el, f.meta, scope, nil)
+		if err != nil {
+			return err
+		}
+		if ok {
+			e.WriteByte('}')
+		} else {
+			e.Truncate(n)
+		}
 	}
 	e.WriteByte('}')
 
+	if f.afterMarshal {
+		if m, ok := valuePtr(el).Interface().(AfterMarshaler); ok {
+			return e.afterMarshal(m, start)
+		}
+	}
+
+	return nil
+}
+
+// afterMarshal replaces resource object written from start with output of AfterMarshaler
+func (e *encoder) afterMarshal(m AfterMarshaler, start int) error {
+	b, err := m.AfterMarshalJSONAPI(append([]byte{}, e.Bytes()[start:]...))
+	if err != nil {
+		return err
+	}
+	e.Truncate(start)
+	e.Write(b)
 	return nil
 }
 
+// canRead returns true if reader allows member for caller
+func (e *encoder) canRead(reader AttributeReader, name string) bool {
+	return reader == nil || reader.CanReadAttribute(e.ctx, name)
+}
+
+// writeMembers writes object members for fields and returns false if nothing was written
+func (e *encoder) writeMembers(el reflect.Value, flds []field, scope Scope, reader AttributeReader) (bool, error) {
+	empty := true
+	for k := range flds {
+		ev := el.FieldByIndex(flds[k].idx)
+		if flds[k].skipEmpty && isEmptyValue(ev) {
+			continue
+		}
+		if !flds[k].allowed(accessRead, scope) || !e.canRead(reader, flds[k].name) {
+			continue
+		}
+		if !empty {
+			e.WriteByte(',')
+		}
+		e.WriteByte('"')
+		e.WriteString(flds[k].name)
+		e.WriteByte('"')
+		e.WriteByte(':')
+		b, err := flds[k].encode(ev)
+		if err != nil {
+			return !empty, err
+		}
+		e.Write(b)
+		empty = false
+	}
+	return !empty, nil
+}
+
+// writeID writes resource id as json string
+func (e *encoder) writeID(id reflect.Value) {
+	if id.Type().Implements(jsonMarshallerType) {
+		m := id.Interface().(json.Marshaler)
+		b, _ := m.MarshalJSON()
+		e.Write(b)
+		return
+	}
+	e.WriteByte('"')
+	switch id.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.Write(strconv.AppendUint(e.buffer[:0], id.Uint(), 10))
+	case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.Write(strconv.AppendInt(e.buffer[:0], id.Int(), 10))
+	case reflect.String:
+		e.WriteString(id.String())
+	}
+	e.WriteByte('"')
+}
+
+// idString returns resource id as string
+func idString(id reflect.Value) string {
+	switch id.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(id.Uint(), 10)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(id.Int(), 10)
+	case reflect.String:
+		return id.String()
+	}
+	if id.Type().Implements(jsonMarshallerType) {
+		var s string
+		b, _ := id.Interface().(json.Marshaler).MarshalJSON()
+		if json.Unmarshal(b, &s) == nil {
+			return s
+		}
+		return string(b)
+	}
+	return fmt.Sprint(id.Interface())
+}
+
+// emptyLink returns true for empty link field
+func emptyLink(v reflect.Value) bool {
+	if l, ok := v.Interface().(Link); ok {
+		return l.Empty()
+	}
+	return isEmptyValue(v)
+}
+
 // isEmptyValue taken from go standard encoding/json package
 func isEmptyValue(v reflect.Value) bool {
 	switch v.Kind() {
@@ -43,7 +43,7 @@ type testStructMarshaler struct {
 }
 
 func (t *testStructMarshaler) MarshalJSONAPI() ([]byte, error) {
-	return []byte("custom"), nil
+	return []byte(`{"custom":"return"}`), nil
 }
 
 type testStructBeforeMarshaler struct {
@@ -58,7 +58,7 @@ func (t *testStructBeforeMarshaler) BeforeMarshalJSONAPI() error {
 
 func TestMarshaler(t *testing.T) {
 	s := testStructMarshaler{ID: 100}
-	want := `custom`
+	want := `{"custom":"return"}`
 	res, err := Marshal(&s)
 	assertNil(t, err)
 	assertEqual(t, want, string(res))
@@ -91,8 +91,8 @@ func TestMarshalRelations(t *testing.T) {
 	assertNil(t, err)
 	assertEqual(t, want, string(res))
 
-	s.Rel1.Links.Self = "self/1"
-	s.Rel1.Links.Related = "rel/1"
+	s.Rel1.Links.Self = Link{Href: "self/1"}
+	s.Rel1.Links.Related = Link{Href: "rel/1"}
 
 	want = `{"id":"100","type":"test-rels","attributes":{"name":"A"},"relationships":{"rel1":{"links":{"self":"self/1","related":"rel/1"}}}}`
 	res, err = Marshal(&s)
@@ -107,6 +107,96 @@ func TestMarshalRelations(t *testing.T) {
 	assertEqual(t, want, string(res))
 }
 
+type testPost struct {
+	ID    uint64 `jsonapi:"id,posts"`
+	Title string `jsonapi:"attr,title"`
+}
+
+type testPhoto struct {
+	ID  string `jsonapi:"id,photos"`
+	URL string `jsonapi:"attr,url"`
+}
+
+type testComment struct {
+	ID          uint64        `jsonapi:"id,comments"`
+	Body        string        `jsonapi:"attr,body"`
+	Commentable interface{}   `jsonapi:"rel,commentable"`
+	Attachments []interface{} `jsonapi:"rel,attachments"`
+	Author      *testPost     `jsonapi:"rel,author"`
+}
+
+func TestMarshalPolymorphicRelations(t *testing.T) {
+	s := testComment{
+		ID:          1,
+		Body:        "nice",
+		Commentable: &testPhoto{ID: "a1", URL: "/a1.png"},
+		Attachments: []interface{}{&testPost{ID: 2}, testPhoto{ID: "b2"}},
+	}
+
+	want := `{"id":"1","type":"comments","attributes":{"body":"nice"},"relationships":{"commentable":{"data":{"type":"photos","id":"a1"}},"attachments":{"data":[{"type":"posts","id":"2"},{"type":"photos","id":"b2"}]},"author":{"data":null}}}`
+	res, err := Marshal(&s)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	s.Commentable = "invalid"
+	_, err = Marshal(&s)
+	assertEqual(t, errMarshalInvalidRelation, err)
+}
+
+type testCustomRelation struct {
+	Data map[string]string `json:"data"`
+}
+
+type testCustomRelations struct {
+	ID    uint64             `jsonapi:"id,customs"`
+	Name  string             `jsonapi:"attr,name"`
+	Owner testCustomRelation `jsonapi:"rel,owner"`
+}
+
+func TestMarshalCustomRelation(t *testing.T) {
+	s := testCustomRelations{ID: 1, Name: "A", Owner: testCustomRelation{Data: map[string]string{"type": "users", "id": "2"}}}
+
+	want := `{"id":"1","type":"customs","attributes":{"name":"A"},"relationships":{"owner":{"data":{"id":"2","type":"users"}}}}`
+	res, err := Marshal(&s)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+}
+
+type testMetaStruct struct {
+	ID      uint64   `jsonapi:"id,test-metas"`
+	Name    string   `jsonapi:"attr,name"`
+	Version int      `jsonapi:"meta,version"`
+	Note    string   `jsonapi:"meta,note,omitempty"`
+	Owner   Relation `jsonapi:"rel,owner"`
+}
+
+func TestMarshalMeta(t *testing.T) {
+	s := testMetaStruct{ID: 1, Name: "A", Version: 3}
+	s.Owner.Meta = map[string]int{"count": 1}
+
+	want := `{"id":"1","type":"test-metas","attributes":{"name":"A"},"relationships":{"owner":{"meta":{"count":1}}},"meta":{"version":3}}`
+	res, err := Marshal(&s)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	s.Owner = Relation{}
+	s.Note = "n"
+	want = `{"id":"1","type":"test-metas","attributes":{"name":"A"},"relationships":{"owner":{}},"meta":{"version":3,"note":"n"}}`
+	res, err = Marshal(&s)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	o := struct {
+		ID   uint64 `jsonapi:"id,test-metas"`
+		Name string `jsonapi:"attr,name"`
+		Note string `jsonapi:"meta,note,omitempty"`
+	}{ID: 1, Name: "A"}
+	want = `{"id":"1","type":"test-metas","attributes":{"name":"A"}}`
+	res, err = Marshal(&o)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+}
+
 func TestMarshal(t *testing.T) {
 	s := testStruct{
 		ID:       100,
@@ -0,0 +1,180 @@
+package jsonapi
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Stable error codes set to Error.Code
+const (
+	CodeRecordNotFound   = "record_not_found"
+	CodePageNotFound     = "page_not_found"
+	CodeUnauthorized     = "unauthorized"
+	CodeInternal         = "internal_error"
+	CodeInvalidAttribute = "invalid_attribute"
+	CodeBadRequest       = "bad_request"
+	CodeForbidden        = "forbidden"
+	CodeInvalidParameter = "invalid_parameter"
+	CodeInvalidHeader    = "invalid_header"
+
+	CodeRequired      = "required"
+	CodeMinLength     = "min_length"
+	CodeMaxLength     = "max_length"
+	CodeMinValue      = "min_value"
+	CodeMaxValue      = "max_value"
+	CodeInvalidFormat = "invalid_format"
+	CodeLength        = "length"
+	CodeOneOf         = "one_of"
+	CodeEmail         = "email"
+	CodeURL           = "url"
+	CodeUUID          = "uuid"
+	CodeTimeBefore    = "time_before"
+	CodeTimeAfter     = "time_after"
+	CodeTimeMin       = "time_min"
+	CodeTimeMax       = "time_max"
+	CodeMinItems      = "min_items"
+	CodeMaxItems      = "max_items"
+	CodeUnique        = "unique"
+	CodeEqualField    = "equal_field"
+	CodeGreaterField  = "greater_field"
+	CodeGreaterThan   = "greater_than"
+	CodeLessThan      = "less_than"
+	CodeInvalidValue  = "invalid_value"
+)
+
+// Catalog provides localized messages.
+// Title of error is looked up by "<code>.title" key and detail by "<code>" key.
+// Detail messages may contain fmt verbs filled with rule arguments,
+// e.g. pointer for required rule and limit for min and max rules.
+// Messages without verbs are used as is.
+type Catalog interface {
+	Message(lang, key string) (string, bool)
+}
+
+// MapCatalog is Catalog with messages by language and key
+// 	jsonapi.SetCatalog(jsonapi.MapCatalog{
+// 		"de": {
+// 			"record_not_found.title":  "Datensatz nicht gefunden",
+// 			"record_not_found":        "Der gesuchte Datensatz existiert nicht",
+// 			"invalid_attribute.title": "Ungültiges Attribut",
+// 			"min_length":              "Mindestlänge ist %v",
+// 		},
+// 	})
+type MapCatalog map[string]map[string]string
+
+// Message returns message for language and key
+func (c MapCatalog) Message(lang, key string) (string, bool) {
+	s, ok := c[lang][key]
+	return s, ok
+}
+
+var catalog = catalogStore{}
+
+type catalogStore struct {
+	sync.RWMutex
+	c Catalog
+}
+
+// SetCatalog sets catalog used for errors localization
+func SetCatalog(c Catalog) {
+	catalog.Lock()
+	catalog.c = c
+	catalog.Unlock()
+}
+
+// message stores catalog codes and arguments for error localization
+type message struct {
+	title  string
+	detail string
+	args   []interface{}
+}
+
+// Localize returns errors with titles and details translated to the first
+// language supported by catalog. Untranslated messages are kept as is.
+// 	errs = errs.Localize(jsonapi.ParseAcceptLanguage(r.Header.Get("Accept-Language"))...)
+func (e Errors) Localize(langs ...string) Errors {
+	catalog.RLock()
+	c := catalog.c
+	catalog.RUnlock()
+	if c == nil || len(langs) == 0 {
+		return e
+	}
+
+	res := Errors{Errors: make([]Error, len(e.Errors))}
+	for k, v := range e.Errors {
+		if v.msg != nil {
+			if s, ok := lookupMessage(c, langs, v.msg.title+".title"); ok {
+				v.Title = s
+			}
+			if v.msg.detail != "" {
+				if s, ok := lookupMessage(c, langs, v.msg.detail); ok {
+					v.Detail = formatMessage(s, v.msg.args)
+				}
+			}
+		}
+		res.Errors[k] = v
+	}
+	return res
+}
+
+// formatMessage fills fmt verbs of translation with arguments.
+// Translations without verbs are used as is.
+func formatMessage(s string, args []interface{}) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	return fmt.Sprintf(s, args...)
+}
+
+func lookupMessage(c Catalog, langs []string, key string) (string, bool) {
+	for _, lang := range langs {
+		if s, ok := c.Message(lang, key); ok {
+			return s, true
+		}
+		if i := strings.IndexByte(lang, '-'); i > 0 {
+			if s, ok := c.Message(lang[:i], key); ok {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ParseAcceptLanguage returns languages from Accept-Language header ordered by quality
+// 	jsonapi.ParseAcceptLanguage("de-AT,de;q=0.9,en;q=0.5") // [de-AT de en]
+func ParseAcceptLanguage(s string) []string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+	items := make([]lang, 0, 4)
+	for _, part := range strings.Split(s, ",") {
+		params := strings.Split(part, ";")
+		l := lang{tag: strings.TrimSpace(params[0]), q: 1}
+		if l.tag == "" || l.tag == "*" {
+			continue
+		}
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					l.q = q
+				}
+			}
+		}
+		if l.q > 0 {
+			items = append(items, l)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].q > items[j].q
+	})
+	res := make([]string, len(items))
+	for k := range items {
+		res[k] = items[k].tag
+	}
+	return res
+}
@@ -0,0 +1,50 @@
+package jsonapi
+
+import "testing"
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assertEqual(t, []string{"de-AT", "de", "en"}, ParseAcceptLanguage("en;q=0.5, de-AT,de;q=0.9, *;q=0.1"))
+	assertEqual(t, []string{}, ParseAcceptLanguage(""))
+}
+
+func TestLocalize(t *testing.T) {
+	SetCatalog(MapCatalog{
+		"de": {
+			"record_not_found.title":  "Datensatz nicht gefunden",
+			"record_not_found":        "Der gesuchte Datensatz existiert nicht",
+			"invalid_attribute.title": "Ungültiges Attribut",
+			"min_length":              "Mindestlänge ist %v",
+		},
+	})
+	defer SetCatalog(nil)
+
+	v := Validator{}
+	v.StringLength("ab", "name", 3, 0)
+	v.AddError(ErrorRecordNotFound)
+	v.AddError(ErrorBadRequest("custom"))
+
+	r := Response{Language: "de-DE,en;q=0.8", Errors: v.Errors}
+	want := `{"errors":[` +
+		`{"code":"min_length","status":"422","source":{"pointer":"/data/attributes/name"},"title":"Ungültiges Attribut","detail":"Mindestlänge ist 3"},` +
+		`{"code":"record_not_found","status":"404","title":"Datensatz nicht gefunden","detail":"Der gesuchte Datensatz existiert nicht"},` +
+		`{"code":"bad_request","status":"400","title":"Bad Request","detail":"custom"}]}`
+	res, err := r.MarshalJSON()
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	assertEqual(t, "min length is3", v.Errors.Errors[0].Detail)
+	assertEqual(t, v.Errors, v.Errors.Localize("fr"))
+}
+
+func TestLocalizeWithoutVerbs(t *testing.T) {
+	SetCatalog(MapCatalog{"de": {"required": "ist erforderlich", "min_length": "ist zu kurz"}})
+	defer SetCatalog(nil)
+
+	v := Validator{}
+	v.Present("", "name")
+	v.StringLength("ab", "title", 3, 0)
+
+	errs := v.Errors.Localize("de")
+	assertEqual(t, "ist erforderlich", errs.Errors[0].Detail)
+	assertEqual(t, "ist zu kurz", errs.Errors[1].Detail)
+}
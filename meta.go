@@ -0,0 +1,84 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Meta is free-form meta object
+type Meta map[string]interface{}
+
+// Merge copies members of provided maps or structures into meta.
+// Later values overwrite earlier ones with the same name.
+// 	m := jsonapi.Meta{"took": 12}
+// 	m.Merge(jsonapi.PaginationMeta(100, 10, 20), struct {
+// 		Deprecation string `json:"deprecation"`
+// 	}{"use /v2/posts"})
+func (m Meta) Merge(items ...interface{}) error {
+	for _, item := range items {
+		switch v := item.(type) {
+		case nil:
+		case Meta:
+			for k := range v {
+				m[k] = v[k]
+			}
+		case map[string]interface{}:
+			for k := range v {
+				m[k] = v[k]
+			}
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			members := map[string]interface{}{}
+			if err := json.Unmarshal(b, &members); err != nil {
+				return fmt.Errorf("jsonapi: can't merge %T into meta, object expected", item)
+			}
+			for k := range members {
+				m[k] = members[k]
+			}
+		}
+	}
+	return nil
+}
+
+// PaginationMeta returns meta with total, limit, offset and page count
+func PaginationMeta(total, limit, offset int) Meta {
+	m := Meta{"total": total, "limit": limit, "offset": offset}
+	if limit > 0 {
+		m["pages"] = (total + limit - 1) / limit
+	}
+	return m
+}
+
+// CursorMeta returns meta with cursors for cursor based pagination. Empty cursors are omitted.
+func CursorMeta(before, after string) Meta {
+	cursors := map[string]string{}
+	if before != "" {
+		cursors["before"] = before
+	}
+	if after != "" {
+		cursors["after"] = after
+	}
+	return Meta{"cursors": cursors}
+}
+
+// AddMeta merges provided maps or structures into Response meta
+// 	r := jsonapi.Response{Data: posts}
+// 	r.AddMeta(jsonapi.PaginationMeta(total, q.Limit, q.Offset))
+// 	r.AddMeta(jsonapi.Meta{"took": time.Since(start).String()})
+func (r *Response) AddMeta(items ...interface{}) error {
+	m, ok := r.Meta.(Meta)
+	if !ok {
+		m = Meta{}
+		if err := m.Merge(r.Meta); err != nil {
+			return err
+		}
+	}
+	if err := m.Merge(items...); err != nil {
+		return err
+	}
+	r.Meta = m
+	return nil
+}
@@ -0,0 +1,24 @@
+package jsonapi
+
+import "testing"
+
+func TestResponseMeta(t *testing.T) {
+	r := Response{}
+	res, err := r.MarshalJSON()
+	assertNil(t, err)
+	assertEqual(t, `{}`, string(res))
+
+	r.Meta = &MetaData{Total: 1}
+	assertNil(t, r.AddMeta(PaginationMeta(25, 10, 20), CursorMeta("", "c2")))
+	assertNil(t, r.AddMeta(struct {
+		Deprecation string `json:"deprecation"`
+	}{"v1"}))
+
+	want := `{"meta":{"cursors":{"after":"c2"},"deprecation":"v1","limit":10,"offset":20,"pages":3,"total":25}}`
+	res, err = r.MarshalJSON()
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	err = r.AddMeta("string")
+	assertEqual(t, "jsonapi: can't merge string into meta, object expected", err.Error())
+}
@@ -0,0 +1,106 @@
+package jsonapi
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Naming converts Go names into member and type names.
+// Member is applied to attribute, meta and relationship fields without name in tag,
+// Type is applied to structure name if type is not set with id tag or JSONType method.
+// Plural pluralizes type names.
+type Naming struct {
+	Member func(name string) string
+	Type   func(name string) string
+	Plural bool
+}
+
+var naming = namingStore{n: Naming{Type: KebabCase}}
+
+type namingStore struct {
+	sync.RWMutex
+	n Naming
+}
+
+// SetNaming sets naming strategy. It must be called before types are prepared with Prepare
+// or registered with RegisterType. Cached metadata of all types is dropped, so names of
+// already used types are recalculated and types prepared before must be prepared again.
+// 	jsonapi.SetNaming(jsonapi.Naming{Member: jsonapi.CamelCase, Type: jsonapi.KebabCase, Plural: true})
+// 	// BlogPost.CreatedAt is "blog-posts" with "createdAt" attribute
+func SetNaming(n Naming) {
+	naming.Lock()
+	naming.n = n
+	naming.Unlock()
+
+	types.reset()
+}
+
+func currentNaming() Naming {
+	naming.RLock()
+	n := naming.n
+	naming.RUnlock()
+	return n
+}
+
+// member returns member name for Go field name
+func (n Naming) member(name string) string {
+	if n.Member == nil {
+		return name
+	}
+	return n.Member(name)
+}
+
+// typeName returns resource type for Go structure name
+func (n Naming) typeName(name string) string {
+	if n.Type != nil {
+		name = n.Type(name)
+	}
+	if n.Plural {
+		name = Pluralize(name)
+	}
+	return name
+}
+
+// KebabCase converts name to kebab case
+// 	KebabCase("UserID") // user-id
+func KebabCase(name string) string {
+	return stringTransform(name, "-")
+}
+
+// SnakeCase converts name to snake case
+// 	SnakeCase("UserID") // user_id
+func SnakeCase(name string) string {
+	return stringTransform(name, "_")
+}
+
+// CamelCase converts name to camel case starting with lower case letter
+// 	CamelCase("UserID") // userId
+func CamelCase(name string) string {
+	words := strings.Split(stringTransform(name, "_"), "_")
+	for i := 1; i < len(words); i++ {
+		if words[i] != "" {
+			r := []rune(words[i])
+			r[0] = unicode.ToUpper(r[0])
+			words[i] = string(r)
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// Pluralize returns plural form of last word of english name
+// 	Pluralize("blog-post") // blog-posts
+// 	Pluralize("category")  // categories
+func Pluralize(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case name == "":
+		return name
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return name + "es"
+	}
+	return name + "s"
+}
@@ -0,0 +1,99 @@
+package jsonapi
+
+import "testing"
+
+func TestNames(t *testing.T) {
+	assertEqual(t, "blog-category", KebabCase("BlogCategory"))
+	assertEqual(t, "user_id", SnakeCase("UserID"))
+	assertEqual(t, "userId", CamelCase("UserID"))
+	assertEqual(t, "createdAt", CamelCase("CreatedAt"))
+	assertEqual(t, "categories", Pluralize("category"))
+	assertEqual(t, "days", Pluralize("day"))
+	assertEqual(t, "boxes", Pluralize("box"))
+	assertEqual(t, "blog-posts", Pluralize("blog-post"))
+}
+
+type testNamed struct {
+	ID        uint64    `jsonapi:"id,named"`
+	Title     string    `jsonapi:"attr"`
+	CreatedAt string    `jsonapi:"attr"`
+	UserID    int       `jsonapi:"attr,user"`
+	MainPost  *testPost `jsonapi:"rel"`
+}
+
+type BlogEntry struct {
+	Key   string `jsonapi:"attr,key"`
+	Value string `jsonapi:"attr,value"`
+}
+
+func (b BlogEntry) JSONID() string { return "Key" }
+
+func TestNaming(t *testing.T) {
+	s := testNamed{ID: 1, Title: "t", CreatedAt: "c", UserID: 2}
+	res, err := Marshal(&s)
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"named","attributes":{"Title":"t","CreatedAt":"c","user":2},"relationships":{"MainPost":{"data":null}}}`, string(res))
+
+	SetNaming(Naming{Member: SnakeCase, Type: SnakeCase, Plural: true})
+	defer SetNaming(Naming{Type: KebabCase})
+
+	res, err = Marshal(&s)
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"named","attributes":{"title":"t","created_at":"c","user":2},"relationships":{"main_post":{"data":null}}}`, string(res))
+
+	res, err = Marshal(BlogEntry{Key: "k", Value: "v"})
+	assertNil(t, err)
+	assertEqual(t, `{"id":"k","type":"blog_entries","attributes":{"key":"k","value":"v"}}`, string(res))
+
+	SetNaming(Naming{Member: CamelCase, Type: KebabCase})
+	d := testNamed{}
+	assertNil(t, Unmarshal([]byte(`{"data":{"type":"named","attributes":{"createdAt":"c"}}}`), &d))
+	assertEqual(t, "c", d.CreatedAt)
+}
+
+func TestValidMember(t *testing.T) {
+	for _, name := range []string{"name", "created-at", "created_at", "first name", "a", "Ünïcode", "x1"} {
+		assertNil(t, validMember(name), name)
+	}
+	for _, name := range []string{"", "-name", "name_", " name", "@context", "a.b", "a:b", "a+b", "a/b", "a\x01"} {
+		assertEqual(t, true, validMember(name) != nil, name)
+	}
+}
+
+type testInvalidMember struct {
+	ID   uint64 `jsonapi:"id,invalids"`
+	Name string `jsonapi:"attr,name!"`
+}
+
+type testReservedMember struct {
+	ID   uint64 `jsonapi:"id,reserved"`
+	Type string `jsonapi:"attr,type"`
+}
+
+type testDuplicateMember struct {
+	ID     uint64    `jsonapi:"id,duplicates"`
+	Author string    `jsonapi:"attr,author"`
+	Writer *testPost `jsonapi:"rel,author"`
+}
+
+func TestInvalidMembers(t *testing.T) {
+	_, err := Marshal(&testInvalidMember{})
+	assertEqual(t, `jsonapi: invalid member name "name!" of jsonapi.testInvalidMember.Name: '!' is not allowed`, err.Error())
+	err = Unmarshal([]byte(`{"data":{"type":"invalids"}}`), &testInvalidMember{})
+	assertEqual(t, true, err != nil)
+
+	_, err = Marshal(&testReservedMember{})
+	assertEqual(t, `jsonapi: member name "type" of jsonapi.testReservedMember.Type is reserved`, err.Error())
+	_, err = Marshal(&testDuplicateMember{})
+	assertEqual(t, `jsonapi: duplicate member name "author" of jsonapi.testDuplicateMember.Writer`, err.Error())
+
+	SetDevelopment(true)
+	defer SetDevelopment(false)
+	defer func() {
+		assertEqual(t, `jsonapi: invalid type "bad type!" of struct { ID uint64 "jsonapi:\"id,bad type!\""; Name string "jsonapi:\"attr\"" }: '!' is not allowed`, recover())
+	}()
+	Marshal(&struct {
+		ID   uint64 `jsonapi:"id,bad type!"`
+		Name string `jsonapi:"attr"`
+	}{})
+}
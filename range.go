@@ -0,0 +1,137 @@
+package jsonapi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Bound is optional inclusive or exclusive range limit. Zero Bound means no limit.
+type Bound struct {
+	num       number
+	orig      interface{}
+	set       bool
+	exclusive bool
+}
+
+// Inclusive returns bound including n. n may be integer or float of any width.
+// 	v.Range(Temperature, "temperature", jsonapi.Inclusive(-10), jsonapi.Inclusive(0))
+func Inclusive(n interface{}) Bound {
+	return newBound(n, false)
+}
+
+// Exclusive returns bound excluding n. n may be integer or float of any width.
+// 	v.Range(Price, "price", jsonapi.Exclusive(0), jsonapi.Bound{}) // price > 0
+func Exclusive(n interface{}) Bound {
+	return newBound(n, true)
+}
+
+func newBound(n interface{}, exclusive bool) Bound {
+	num, ok := toNumber(reflect.ValueOf(n))
+	if !ok {
+		panic(fmt.Sprintf("jsonapi: invalid range bound %T, number expected", n))
+	}
+	return Bound{num: num, orig: n, set: true, exclusive: exclusive}
+}
+
+// legacyBound returns inclusive bound for positive n and no bound otherwise
+func legacyBound(n interface{}, positive bool) Bound {
+	if !positive {
+		return Bound{}
+	}
+	return Inclusive(n)
+}
+
+// Range validates number of any integer or float width is within bounds.
+// Nil pointer passes, value which is not a number panics.
+// 	v.Range(Int8Value, "level", jsonapi.Inclusive(int8(-10)), jsonapi.Inclusive(int8(0)))
+// 	v.Range(Uint64Value, "count", jsonapi.Inclusive(0), jsonapi.Bound{})
+func (v *Validator) Range(value interface{}, pointer string, min, max Bound) {
+	if v.skip(pointer, false) {
+		return
+	}
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	if !rv.IsValid() {
+		return
+	}
+	n, ok := toNumber(rv)
+	if !ok {
+		panic(fmt.Sprintf("jsonapi: invalid range value %T, number expected", value))
+	}
+	if min.set {
+		c := n.cmp(min.num)
+		switch {
+		case min.exclusive && c <= 0:
+			v.Invalid(pointer, CodeGreaterThan, fmt.Sprint("value must be greater than ", min.orig), min.orig)
+		case !min.exclusive && c < 0:
+			v.Invalid(pointer, CodeMinValue, fmt.Sprint("min value is", min.orig), min.orig)
+		}
+	}
+	if max.set {
+		c := n.cmp(max.num)
+		switch {
+		case max.exclusive && c >= 0:
+			v.Invalid(pointer, CodeLessThan, fmt.Sprint("value must be less than ", max.orig), max.orig)
+		case !max.exclusive && c > 0:
+			v.Invalid(pointer, CodeMaxValue, fmt.Sprint("max value is", max.orig), max.orig)
+		}
+	}
+}
+
+// number keeps integer and float values without loss of precision
+type number struct {
+	kind reflect.Kind
+	i    int64
+	u    uint64
+	f    float64
+}
+
+func toNumber(v reflect.Value) (number, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{kind: reflect.Int64, i: v.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return number{kind: reflect.Uint64, u: v.Uint()}, true
+	case reflect.Float32, reflect.Float64:
+		return number{kind: reflect.Float64, f: v.Float()}, true
+	}
+	return number{}, false
+}
+
+func (n number) float() float64 {
+	switch n.kind {
+	case reflect.Int64:
+		return float64(n.i)
+	case reflect.Uint64:
+		return float64(n.u)
+	}
+	return n.f
+}
+
+// cmp returns -1 if n is less than m, 0 if equal and 1 if greater
+func (n number) cmp(m number) int {
+	switch {
+	case n.kind == reflect.Int64 && m.kind == reflect.Int64:
+		return cmpInt(n.i < m.i, n.i > m.i)
+	case n.kind == reflect.Uint64 && m.kind == reflect.Uint64:
+		return cmpInt(n.u < m.u, n.u > m.u)
+	case n.kind == reflect.Int64 && m.kind == reflect.Uint64:
+		if n.i < 0 {
+			return -1
+		}
+		return cmpInt(uint64(n.i) < m.u, uint64(n.i) > m.u)
+	case n.kind == reflect.Uint64 && m.kind == reflect.Int64:
+		return -m.cmp(n)
+	}
+	a, b := n.float(), m.float()
+	return cmpInt(a < b, a > b)
+}
+
+func cmpInt(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,297 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+var registry = typeRegistry{m: make(map[string]reflect.Type)}
+
+var relationType = reflect.TypeOf(Relation{})
+
+type typeRegistry struct {
+	sync.RWMutex
+	m map[string]reflect.Type
+}
+
+func (r *typeRegistry) lookup(stype string) (reflect.Type, bool) {
+	r.RLock()
+	t, ok := r.m[stype]
+	r.RUnlock()
+	return t, ok
+}
+
+// RegisterType registers resource structures used for instantiating
+// polymorphic relationships and included resources on unmarshal
+// 	jsonapi.RegisterType(&Post{}, &Photo{})
+func RegisterType(items ...interface{}) {
+	for _, i := range items {
+		t := reflect.TypeOf(i)
+		if t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			panic(fmt.Sprintf("jsonapi: can't register %T, struct expected", i))
+		}
+		f := types.get(t)
+		if f.err != nil {
+			panic(f.err.Error())
+		}
+		registry.Lock()
+		registry.m[f.stype] = t
+		registry.Unlock()
+	}
+}
+
+// resourceIdentifier is resource linkage item
+type resourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+func (r resourceIdentifier) key() string {
+	return r.Type + "/" + r.ID
+}
+
+// resource is resource object used for decoding included resources
+type resource struct {
+	resourceIdentifier
+	Attributes    map[string]json.RawMessage `json:"attributes"`
+	Relationships map[string]json.RawMessage `json:"relationships"`
+	Meta          map[string]json.RawMessage `json:"meta"`
+	Links         map[string]json.RawMessage `json:"links"`
+	// pointer is JSON pointer of resource object used in error sources
+	pointer string
+}
+
+// marshalRelation writes relationship object. Relation, json.Marshaler and values
+// which are not resource linkage are written as is, resources with id and interfaces
+// are written as resource linkage. Generated links are added unless set explicitly.
+func (e *encoder) marshalRelation(v reflect.Value, links Links) error {
+	t := v.Type()
+	if t == relationType {
+		r := v.Interface().(Relation)
+		if r.Links.Self.Empty() {
+			r.Links.Self = links.Self
+		}
+		if r.Links.Related.Empty() {
+			r.Links.Related = links.Related
+		}
+		b, err := r.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		e.Write(b)
+		return nil
+	}
+	if t.Implements(jsonMarshallerType) || !linkage(t) {
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return err
+		}
+		e.Write(b)
+		return nil
+	}
+	e.WriteByte('{')
+	if !links.Empty() {
+		e.WriteString(`"links":`)
+		b, err := links.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		e.Write(b)
+		e.WriteByte(',')
+	}
+	e.WriteString(`"data":`)
+	if err := e.marshalLinkage(v); err != nil {
+		return err
+	}
+	e.WriteByte('}')
+	return nil
+}
+
+// linkage returns true if relationship of type t is resource linkage:
+// structure with id, interface or pointer, slice or array of them
+func linkage(t reflect.Type) bool {
+	t = elemType(t)
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Struct:
+		return t != relationType && len(types.get(t).id) > 0
+	}
+	return false
+}
+
+// marshalLinkage writes resource identifier objects using type and id of every item
+func (e *encoder) marshalLinkage(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			e.WriteString("null")
+			return nil
+		}
+		return e.marshalLinkage(v.Elem())
+	case reflect.Slice, reflect.Array:
+		e.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				e.WriteByte(',')
+			}
+			if err := e.marshalLinkage(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		e.WriteByte(']')
+		return nil
+	case reflect.Struct:
+		f := types.get(v.Type())
+		if f.err != nil {
+			return f.err
+		}
+		if len(f.id) == 0 {
+			return errMarshalInvalidRelation
+		}
+		e.WriteString(`{"type":"`)
+		e.WriteString(f.stype)
+		e.WriteString(`","id":`)
+		e.writeID(v.FieldByIndex(f.id))
+		e.WriteByte('}')
+		return nil
+	}
+	return errMarshalInvalidRelation
+}
+
+// indexIncluded stores included resources by type and id
+func (d *decoder) indexIncluded(items []json.RawMessage) error {
+	d.included = make(map[string]*resource, len(items))
+	d.resolved = make(map[string]reflect.Value)
+	for i, raw := range items {
+		res := &resource{pointer: "/included/" + strconv.Itoa(i)}
+		if err := json.Unmarshal(raw, res); err != nil {
+			return err
+		}
+		d.included[res.key()] = res
+	}
+	return nil
+}
+
+// unmarshalRelation sets relationship field from resource linkage.
+// It reports false when relationship object has no data member.
+func (d *decoder) unmarshalRelation(raw json.RawMessage, v reflect.Value, name string, scope Scope) (bool, error) {
+	rel := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(raw, &rel); err != nil {
+		return false, err
+	}
+
+	data := bytes.TrimSpace(rel.Data)
+	switch {
+	case len(data) == 0:
+		return false, nil
+	case bytes.Equal(data, []byte("null")):
+		v.Set(reflect.Zero(v.Type()))
+		return true, nil
+	case data[0] == '[':
+		if v.Kind() != reflect.Slice {
+			return false, fmt.Errorf("jsonapi: can't unmarshal to-many relationship '%s' into %s", name, v.Type())
+		}
+		items := []resourceIdentifier{}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return false, err
+		}
+		s := reflect.MakeSlice(v.Type(), 0, len(items))
+		for _, item := range items {
+			nv, err := d.instantiate(item, v.Type().Elem(), scope)
+			if err != nil {
+				return false, err
+			}
+			s = reflect.Append(s, nv)
+		}
+		v.Set(s)
+	default:
+		item := resourceIdentifier{}
+		if err := json.Unmarshal(data, &item); err != nil {
+			return false, err
+		}
+		nv, err := d.instantiate(item, v.Type(), scope)
+		if err != nil {
+			return false, err
+		}
+		v.Set(nv)
+	}
+	return true, nil
+}
+
+// instantiate creates resource for linkage item using registered types
+// and fills it from included resources if present
+func (d *decoder) instantiate(item resourceIdentifier, target reflect.Type, scope Scope) (reflect.Value, error) {
+	key := item.key()
+	nv, ok := d.resolved[key]
+	if !ok {
+		t, ok := registry.lookup(item.Type)
+		if !ok {
+			t = target
+			if t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			if t.Kind() != reflect.Struct || types.get(t).stype != item.Type {
+				return nv, fmt.Errorf("jsonapi: unknown relationship type '%s'", item.Type)
+			}
+		}
+
+		nv = reflect.New(t)
+		if err := setID(nv.Elem(), item.ID); err != nil {
+			return nv, err
+		}
+		d.resolved[key] = nv
+
+		if res, ok := d.included[key]; ok {
+			sub := decoder{ctx: d.ctx, op: d.op, included: d.included, resolved: d.resolved}
+			if err := sub.decode(nv, res, scope); err != nil {
+				return nv, err
+			}
+		}
+	}
+
+	switch {
+	case nv.Type().AssignableTo(target):
+		return nv, nil
+	case nv.Elem().Type().AssignableTo(target):
+		return nv.Elem(), nil
+	}
+	return nv, fmt.Errorf("jsonapi: can't assign item of type '%s' to %s", item.Type, target)
+}
+
+// setID sets resource id field from string value
+func setID(v reflect.Value, id string) error {
+	f := types.get(v.Type())
+	if len(f.id) == 0 {
+		return nil
+	}
+	fv := v.FieldByIndex(f.id)
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(id)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	default:
+		return json.Unmarshal([]byte(strconv.Quote(id)), fv.Addr().Interface())
+	}
+	return nil
+}
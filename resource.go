@@ -0,0 +1,185 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+var errMarshalInvalidResource = errors.New("jsonapi: Marshaler returned invalid resource object")
+
+// ResourceMarshaler interface returns resource object which is serialized and validated
+// by encoder. Attributes and relationships are selected with Scopes and AttributeReader
+// like tagged fields and links are generated with Linker.
+// 	func (p *Post) MarshalJSONAPIResource() (jsonapi.ResourceObject, error) {
+// 		return jsonapi.ResourceObject{
+// 			ID:         strconv.Itoa(p.ID),
+// 			Type:       "posts",
+// 			Attributes: map[string]interface{}{"title": p.Title, "notes": p.Notes},
+// 			Scopes:     map[string]string{"notes": "admin"},
+// 		}, nil
+// 	}
+type ResourceMarshaler interface {
+	MarshalJSONAPIResource() (ResourceObject, error)
+}
+
+var resourceMarshalerType = reflect.TypeOf(new(ResourceMarshaler)).Elem()
+
+// ResourceObject is resource object returned by ResourceMarshaler
+type ResourceObject struct {
+	ID         string
+	Type       string
+	Attributes map[string]interface{}
+	// Relationships values are Relation, resources or slices of resources
+	Relationships map[string]interface{}
+	Links         map[string]Link
+	Meta          map[string]interface{}
+	// Scopes are scope tags of attributes and relationships by name
+	Scopes map[string]string
+}
+
+// marshalResource writes resource object returned by ResourceMarshaler
+func (e *encoder) marshalResource(o ResourceObject, scope Scope, reader AttributeReader) error {
+	if o.ID == "" || !validKey(o.Type) {
+		return errMarshalInvalidResource
+	}
+	scopes := make(map[string]fieldScope, len(o.Scopes))
+	for name, tag := range o.Scopes {
+		s, err := parseScope(tag)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errMarshalInvalidResource, err)
+		}
+		scopes[name] = s
+	}
+	allowed := func(name string) bool {
+		return scopes[name].allowed(accessRead, scope) && e.canRead(reader, name)
+	}
+
+	e.WriteString(`{"id":`)
+	b, _ := json.Marshal(o.ID)
+	e.Write(b)
+	e.WriteString(`,"type":"`)
+	e.WriteString(o.Type)
+	e.WriteByte('"')
+
+	if len(o.Attributes) > 0 {
+		n := e.Len()
+		e.WriteString(`,"attributes":`)
+		ok, err := e.writeMap(o.Attributes, allowed)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			e.Truncate(n)
+		}
+	}
+
+	links := make(map[string]interface{}, len(o.Links)+1)
+	for k, v := range o.Links {
+		links[k] = v
+	}
+	if self := e.linker.resourceLink(o.Type, o.ID); self != "" && o.Links["self"].Empty() {
+		links["self"] = Link{Href: self}
+	}
+	if len(links) > 0 {
+		e.WriteString(`,"links":`)
+		if _, err := e.writeMap(links, nil); err != nil {
+			return err
+		}
+	}
+
+	names := sortedKeys(o.Relationships)
+	n := e.Len()
+	e.WriteString(`,"relationships":{`)
+	empty := true
+	for _, name := range names {
+		if !allowed(name) {
+			continue
+		}
+		if !validKey(name) {
+			return errMarshalInvalidResource
+		}
+		if !empty {
+			e.WriteByte(',')
+		}
+		empty = false
+		b, _ := json.Marshal(name)
+		e.Write(b)
+		e.WriteByte(':')
+		v := reflect.ValueOf(o.Relationships[name])
+		if !v.IsValid() {
+			e.WriteString(`{"data":null}`)
+			continue
+		}
+		if err := e.marshalRelation(v, e.linker.relationLinks(o.Type, o.ID, name)); err != nil {
+			return err
+		}
+	}
+	if empty {
+		e.Truncate(n)
+	} else {
+		e.WriteByte('}')
+	}
+
+	if len(o.Meta) > 0 {
+		e.WriteString(`,"meta":`)
+		if _, err := e.writeMap(o.Meta, nil); err != nil {
+			return err
+		}
+	}
+	e.WriteByte('}')
+	return nil
+}
+
+// writeMap writes object members sorted by name skipping members which are not allowed
+// and returns false if no member was written
+func (e *encoder) writeMap(m interface{}, allowed func(name string) bool) (bool, error) {
+	v := reflect.ValueOf(m)
+	e.WriteByte('{')
+	empty := true
+	for _, name := range sortedKeys(m) {
+		if allowed != nil && !allowed(name) {
+			continue
+		}
+		if !validKey(name) {
+			return !empty, errMarshalInvalidResource
+		}
+		b, err := json.Marshal(v.MapIndex(reflect.ValueOf(name)).Interface())
+		if err != nil {
+			return !empty, err
+		}
+		if !empty {
+			e.WriteByte(',')
+		}
+		empty = false
+		k, _ := json.Marshal(name)
+		e.Write(k)
+		e.WriteByte(':')
+		e.Write(b)
+	}
+	e.WriteByte('}')
+	return !empty, nil
+}
+
+// sortedKeys returns sorted keys of map with string keys
+func sortedKeys(m interface{}) []string {
+	v := reflect.ValueOf(m)
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validResource returns error if b is not resource object
+func validResource(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || b[0] != '{' || !json.Valid(b) {
+		return fmt.Errorf("%w: %.32q", errMarshalInvalidResource, b)
+	}
+	return nil
+}
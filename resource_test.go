@@ -0,0 +1,78 @@
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type testInvalidMarshaler struct{}
+
+func (t testInvalidMarshaler) MarshalJSONAPI() ([]byte, error) {
+	return []byte("custom"), nil
+}
+
+type testResourceMarshaler struct {
+	ID    string
+	Notes string
+	Post  *testPost
+}
+
+func (t *testResourceMarshaler) MarshalJSONAPIResource() (ResourceObject, error) {
+	return ResourceObject{
+		ID:            t.ID,
+		Type:          "customs",
+		Attributes:    map[string]interface{}{"notes": t.Notes, "secret": "s", "count": 1},
+		Relationships: map[string]interface{}{"post": t.Post, "none": nil},
+		Links:         map[string]Link{"docs": {Href: "/docs"}},
+		Meta:          map[string]interface{}{"v": 1},
+		Scopes:        map[string]string{"notes": "read:admin", "post": "admin"},
+	}, nil
+}
+
+func (t *testResourceMarshaler) CanReadAttribute(ctx context.Context, name string) bool {
+	return name != "secret"
+}
+
+func TestMarshalInvalidMarshaler(t *testing.T) {
+	_, err := Marshal([]testInvalidMarshaler{{}})
+	assertEqual(t, true, errors.Is(err, errMarshalInvalidResource))
+}
+
+func TestResourceMarshaler(t *testing.T) {
+	s := []*testResourceMarshaler{{ID: "a", Notes: "n", Post: &testPost{ID: 2}}}
+	res, err := Marshal(s)
+	assertNil(t, err)
+	assertEqual(t, `[{"id":"a","type":"customs","attributes":{"count":1,"notes":"n"},"links":{"docs":"/docs"},"relationships":{"none":{"data":null},"post":{"data":{"type":"posts","id":"2"}}},"meta":{"v":1}}]`, string(res))
+
+	res, err = MarshalScope(s, Roles("user"))
+	assertNil(t, err)
+	assertEqual(t, `[{"id":"a","type":"customs","attributes":{"count":1},"links":{"docs":"/docs"},"relationships":{"none":{"data":null}},"meta":{"v":1}}]`, string(res))
+
+	SetLinker(&Linker{BaseURL: "/api"})
+	defer SetLinker(nil)
+	res, err = (&Response{Included: s[0], Roles: &Scope{Roles: []string{"user"}, Strict: true}}).MarshalJSON()
+	assertNil(t, err)
+	assertEqual(t, `{"included":{"id":"a","type":"customs","links":{"docs":"/docs","self":"/api/customs/a"},"meta":{"v":1}}}`, string(res))
+
+	_, err = Marshal(&testResourceMarshaler{})
+	assertEqual(t, errMarshalInvalidResource, err)
+}
+
+type testAfterResource struct {
+	ID string
+}
+
+func (t testAfterResource) MarshalJSONAPIResource() (ResourceObject, error) {
+	return ResourceObject{ID: t.ID, Type: "afters"}, nil
+}
+
+func (t testAfterResource) AfterMarshalJSONAPI(b []byte) ([]byte, error) {
+	return append(b[:len(b)-1], `,"meta":{"after":true}}`...), nil
+}
+
+func TestResourceMarshalerAfterMarshal(t *testing.T) {
+	res, err := Marshal(testAfterResource{ID: "1"})
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"afters","meta":{"after":true}}`, string(res))
+}
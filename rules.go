@@ -0,0 +1,512 @@
+package jsonapi
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+var uuidRegexp = regexp.MustCompile(`\A[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\z`)
+
+// rule is validation rule parsed from validate tag
+type rule struct {
+	name string
+	arg  string
+	num  float64
+	re   *regexp.Regexp
+	set  []string
+	fn   RuleFunc
+}
+
+// parseRules parses validate tag. Rules are separated by comma,
+// format rule takes the rest of the tag so it must be the last one.
+// 	Name  string `jsonapi:"attr,name" validate:"required,min=3,max=20"`
+// 	Role  string `jsonapi:"attr,role" validate:"oneof=admin user"`
+// 	Login string `jsonapi:"attr,login" validate:"required,format=^[a-z0-9,]+$"`
+func parseRules(fd reflect.StructField) ([]rule, error) {
+	tag := fd.Tag.Get("validate")
+	if tag == "" {
+		return nil, nil
+	}
+
+	rules := []rule{}
+	for tag != "" {
+		var item string
+		if strings.HasPrefix(tag, "format=") {
+			item, tag = tag, ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			item, tag = tag[:i], tag[i+1:]
+		} else {
+			item, tag = tag, ""
+		}
+
+		r := rule{name: item}
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			r.name, r.arg = item[:i], item[i+1:]
+		}
+
+		switch r.name {
+		case "required", "email", "url", "uuid":
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(r.arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s rule value '%s'", r.name, r.arg)
+			}
+			r.num = n
+		case "format":
+			re, err := regexp.Compile(r.arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid format rule: %v", err)
+			}
+			r.re = re
+		case "oneof":
+			r.set = strings.Fields(r.arg)
+		default:
+			fn, ok := customRules.lookup(r.name)
+			if !ok {
+				return nil, fmt.Errorf("unknown validation rule '%s'", r.name)
+			}
+			r.fn = fn
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Validate checks item attributes with rules from validate tags
+// 	type User struct {
+// 		ID    uint64 `jsonapi:"id,users"`
+// 		Email string `jsonapi:"attr,email" validate:"required,email"`
+// 	}
+func Validate(i interface{}) error {
+	v := interfacePtr(i)
+	if !v.IsValid() {
+		return errMarshalInvalidData
+	}
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return errMarshalInvalidData
+	}
+	if !v.CanAddr() {
+		nv := reflect.New(v.Type()).Elem()
+		nv.Set(v)
+		v = nv
+	}
+
+	f := types.get(v.Type())
+	if f.err != nil {
+		return f.err
+	}
+	val := Validator{}
+	val.rules(v, f, Scope{})
+	return val.Verify()
+}
+
+// rules validates attributes with rules from validate tags and registered structure validators
+func (v *Validator) rules(el reflect.Value, f *fields, scope Scope) {
+	for _, attr := range f.attrs {
+		if attr.readonly || !attr.allowed(accessWrite, scope) {
+			continue
+		}
+		v.fieldRules(el, f.attrs, attr)
+	}
+	v.structRules(el)
+}
+
+// fieldRules validates field value with rules and nested structures with their validate tags
+func (v *Validator) fieldRules(parent reflect.Value, siblings []field, fd field) {
+	value := parent.FieldByIndex(fd.idx)
+	for _, r := range fd.rules {
+		if v.skip(fd.name, r.name == "required") {
+			continue
+		}
+		if r.fn != nil {
+			r.fn(v, RuleContext{
+				Resource: parent.Addr().Interface(),
+				Value:    value.Interface(),
+				Pointer:  fd.name,
+				Param:    r.arg,
+				parent:   parent,
+				fields:   siblings,
+			})
+			continue
+		}
+		if !v.rule(r, value, fd.name) {
+			return
+		}
+	}
+	v.nested(value, fd.name)
+}
+
+// structRules runs structure validators registered for type of value
+func (v *Validator) structRules(value reflect.Value) {
+	if fn, ok := structValidators.lookup(value.Type()); ok {
+		fn(v, value.Addr().Interface())
+	}
+}
+
+// nested validates fields of nested structures and slices of structures
+func (v *Validator) nested(value reflect.Value, pointer string) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		nt := nestedFields(value.Type())
+		if nt.err != nil {
+			v.AddError(nt.err)
+			return
+		}
+		_, ok := structValidators.lookup(value.Type())
+		if !nt.validated && !ok {
+			return
+		}
+		if !value.CanAddr() {
+			nv := reflect.New(value.Type()).Elem()
+			nv.Set(value)
+			value = nv
+		}
+		v.Nested(pointer, func(n *Validator) {
+			for _, fd := range nt.fields {
+				n.fieldRules(value, nt.fields, fd)
+			}
+			n.structRules(value)
+		})
+	case reflect.Slice, reflect.Array:
+		t := value.Type().Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct && t.Kind() != reflect.Interface {
+			return
+		}
+		for i := 0; i < value.Len(); i++ {
+			v.nested(value.Index(i), pointer+"/"+strconv.Itoa(i))
+		}
+	}
+}
+
+var nestedTypes = nestedCache{m: make(map[reflect.Type]nestedType)}
+
+type nestedCache struct {
+	sync.RWMutex
+	m map[reflect.Type]nestedType
+}
+
+// nestedType stores fields of nested structure with names taken from json tags
+type nestedType struct {
+	fields []field
+	// validated is true if any field has validate tag or may contain nested structures
+	validated bool
+	err       error
+}
+
+// nestedFields returns fields of nested structure
+func nestedFields(t reflect.Type) nestedType {
+	nestedTypes.RLock()
+	nt, ok := nestedTypes.m[t]
+	nestedTypes.RUnlock()
+	if ok {
+		return nt
+	}
+
+	for _, idx := range typeFields(t, []int{}) {
+		fd := t.FieldByIndex(idx)
+		name := fd.Name
+		if tag := fd.Tag.Get("json"); tag != "" {
+			if tag = strings.Split(tag, ",")[0]; tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+		}
+
+		rules, err := parseRules(fd)
+		if err != nil && nt.err == nil {
+			nt.err = fmt.Errorf("jsonapi: invalid tag of %s.%s: %v", t, fd.Name, err)
+		}
+		fld := field{idx: idx, name: name, rules: rules}
+		ft := fd.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case len(fld.rules) > 0:
+			nt.validated = true
+		case ft.Kind() == reflect.Struct && ft != timeType:
+			nt.validated = true
+		case ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array:
+			nt.validated = true
+		}
+		nt.fields = append(nt.fields, fld)
+	}
+
+	nestedTypes.Lock()
+	nestedTypes.m[t] = nt
+	nestedTypes.Unlock()
+
+	if nt.err != nil && development() {
+		panic(nt.err.Error())
+	}
+	return nt
+}
+
+// rule validates value and returns false if value is missing and
+// the rest of rules should be skipped
+func (v *Validator) rule(r rule, value reflect.Value, pointer string) bool {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			if r.name == "required" {
+				v.Invalid(pointer, CodeRequired, pointer+" required", pointer)
+			}
+			return false
+		}
+		value = value.Elem()
+	}
+
+	switch r.name {
+	case "required":
+		if value.IsZero() {
+			v.Invalid(pointer, CodeRequired, pointer+" required", pointer)
+			return false
+		}
+	case "min", "max", "len":
+		v.size(r, value, pointer)
+	case "format":
+		if value.Kind() == reflect.String && value.Len() > 0 && !r.re.MatchString(value.String()) {
+			v.Invalid(pointer, CodeInvalidFormat, "invalid format")
+		}
+	case "oneof":
+		s := fmt.Sprint(value.Interface())
+		for _, item := range r.set {
+			if item == s {
+				return true
+			}
+		}
+		v.Invalid(pointer, CodeOneOf, "must be one of: "+strings.Join(r.set, ", "), strings.Join(r.set, ", "))
+	case "email":
+		if value.Kind() == reflect.String && value.Len() > 0 && !validEmail(value.String()) {
+			v.Invalid(pointer, CodeEmail, "invalid email")
+		}
+	case "url":
+		if value.Kind() == reflect.String && value.Len() > 0 && !validURL(value.String()) {
+			v.Invalid(pointer, CodeURL, "invalid url")
+		}
+	case "uuid":
+		if value.Kind() == reflect.String && value.Len() > 0 && !uuidRegexp.MatchString(value.String()) {
+			v.Invalid(pointer, CodeUUID, "invalid uuid")
+		}
+	}
+	return true
+}
+
+// size validates min, max and len rules for numbers, strings, slices and maps
+func (v *Validator) size(r rule, value reflect.Value, pointer string) {
+	var n float64
+	length, items := true, false
+	switch value.Kind() {
+	case reflect.String:
+		n = float64(utf8.RuneCountInString(value.String()))
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n, items = float64(value.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, length = float64(value.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, length = float64(value.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		n, length = value.Float(), false
+	default:
+		return
+	}
+
+	limit := strconv.FormatFloat(r.num, 'f', -1, 64)
+	switch {
+	case r.name == "min" && n < r.num && items:
+		v.Invalid(pointer, CodeMinItems, "must have at least "+limit+" items", limit)
+	case r.name == "max" && n > r.num && items:
+		v.Invalid(pointer, CodeMaxItems, "must have at most "+limit+" items", limit)
+	case r.name == "min" && n < r.num && length:
+		v.Invalid(pointer, CodeMinLength, "min length is"+limit, limit)
+	case r.name == "min" && n < r.num:
+		v.Invalid(pointer, CodeMinValue, "min value is"+limit, limit)
+	case r.name == "max" && n > r.num && length:
+		v.Invalid(pointer, CodeMaxLength, "max length is"+limit, limit)
+	case r.name == "max" && n > r.num:
+		v.Invalid(pointer, CodeMaxValue, "max value is"+limit, limit)
+	case r.name == "len" && n != r.num:
+		v.Invalid(pointer, CodeLength, "length must be "+limit, limit)
+	}
+}
+
+func validEmail(s string) bool {
+	a, err := mail.ParseAddress(s)
+	return err == nil && a.Address == s
+}
+
+func validURL(s string) bool {
+	u, err := url.ParseRequestURI(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// RuleContext is passed to custom validation rules
+type RuleContext struct {
+	// Resource is pointer to structure containing validated field
+	Resource interface{}
+	// Value is validated field value
+	Value interface{}
+	// Pointer is attribute or member name used for errors
+	Pointer string
+	// Param is rule parameter from validate tag
+	Param  string
+	parent reflect.Value
+	fields []field
+}
+
+// Field returns value of other field of the same structure by attribute or member name
+func (c RuleContext) Field(name string) (interface{}, bool) {
+	for _, fd := range c.fields {
+		if fd.name == name {
+			return c.parent.FieldByIndex(fd.idx).Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// RuleFunc validates field and adds errors to Validator
+type RuleFunc func(v *Validator, c RuleContext)
+
+var customRules = ruleRegistry{m: map[string]RuleFunc{
+	"eqfield": eqFieldRule,
+	"gtfield": gtFieldRule,
+}}
+
+type ruleRegistry struct {
+	sync.RWMutex
+	m map[string]RuleFunc
+}
+
+func (r *ruleRegistry) lookup(name string) (RuleFunc, bool) {
+	r.RLock()
+	fn, ok := r.m[name]
+	r.RUnlock()
+	return fn, ok
+}
+
+// RegisterRule registers named rule for validate tags.
+// Rules must be registered before types using them are marshalled or unmarshalled.
+// 	jsonapi.RegisterRule("even", func(v *jsonapi.Validator, c jsonapi.RuleContext) {
+// 		if c.Value.(int)%2 != 0 {
+// 			v.Invalid(c.Pointer, "even", "must be even")
+// 		}
+// 	})
+// 	Count int `jsonapi:"attr,count" validate:"required,even"`
+func RegisterRule(name string, fn RuleFunc) {
+	if name == "" || strings.ContainsAny(name, ",=") || fn == nil {
+		panic(fmt.Sprintf("jsonapi: invalid rule '%s'", name))
+	}
+	customRules.Lock()
+	customRules.m[name] = fn
+	customRules.Unlock()
+}
+
+var structValidators = structRegistry{m: make(map[reflect.Type]func(*Validator, interface{}))}
+
+type structRegistry struct {
+	sync.RWMutex
+	m map[reflect.Type]func(*Validator, interface{})
+}
+
+func (r *structRegistry) lookup(t reflect.Type) (func(*Validator, interface{}), bool) {
+	r.RLock()
+	fn, ok := r.m[t]
+	r.RUnlock()
+	return fn, ok
+}
+
+// RegisterStructValidator registers validator for structure type. It receives pointer
+// to the whole structure after field rules and may add errors for any pointers.
+// Validators of nested structures use pointers relative to the nested attribute.
+// 	jsonapi.RegisterStructValidator(&Event{}, func(v *jsonapi.Validator, i interface{}) {
+// 		e := i.(*Event)
+// 		if !e.EndsAt.After(e.StartsAt) {
+// 			v.Invalid("ends-at", "ends_before_start", "must be after starts-at")
+// 		}
+// 	})
+func RegisterStructValidator(sample interface{}, fn func(v *Validator, item interface{})) {
+	t := reflect.TypeOf(sample)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || fn == nil {
+		panic(fmt.Sprintf("jsonapi: can't register validator for %T", sample))
+	}
+	structValidators.Lock()
+	structValidators.m[t] = fn
+	structValidators.Unlock()
+}
+
+// eqFieldRule validates field is equal to other field
+// 	PasswordConfirmation string `jsonapi:"attr,password-confirmation" validate:"eqfield=password"`
+func eqFieldRule(v *Validator, c RuleContext) {
+	other, ok := c.Field(c.Param)
+	if !ok {
+		return
+	}
+	a, b := reflect.Indirect(reflect.ValueOf(c.Value)), reflect.Indirect(reflect.ValueOf(other))
+	if !a.IsValid() {
+		return
+	}
+	if !b.IsValid() || !reflect.DeepEqual(a.Interface(), b.Interface()) {
+		v.Invalid(c.Pointer, CodeEqualField, "must be equal to "+c.Param, c.Param)
+	}
+}
+
+// gtFieldRule validates field is greater than other field. Supports numbers, strings and time.
+// 	EndsAt time.Time `jsonapi:"attr,ends-at" validate:"gtfield=starts-at"`
+func gtFieldRule(v *Validator, c RuleContext) {
+	other, ok := c.Field(c.Param)
+	if !ok {
+		return
+	}
+	a, b := reflect.Indirect(reflect.ValueOf(c.Value)), reflect.Indirect(reflect.ValueOf(other))
+	if !a.IsValid() || !b.IsValid() || a.Kind() != b.Kind() {
+		return
+	}
+
+	var gt bool
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		gt = a.Int() > b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		gt = a.Uint() > b.Uint()
+	case reflect.Float32, reflect.Float64:
+		gt = a.Float() > b.Float()
+	case reflect.String:
+		gt = a.String() > b.String()
+	case reflect.Struct:
+		t1, ok1 := a.Interface().(time.Time)
+		t2, ok2 := b.Interface().(time.Time)
+		if !ok1 || !ok2 {
+			return
+		}
+		gt = t1.After(t2)
+	default:
+		return
+	}
+	if !gt {
+		v.Invalid(c.Pointer, CodeGreaterField, "must be greater than "+c.Param, c.Param)
+	}
+}
@@ -0,0 +1,198 @@
+package jsonapi
+
+import (
+	"testing"
+	"time"
+)
+
+type testValidated struct {
+	ID    uint64   `jsonapi:"id,test-validated"`
+	Name  string   `jsonapi:"attr,name" validate:"required,min=3,max=5"`
+	Age   int      `jsonapi:"attr,age" validate:"min=18"`
+	Code  string   `jsonapi:"attr,code" validate:"len=2,format=^[A-Z,]+$"`
+	Role  string   `jsonapi:"attr,role" validate:"oneof=admin user"`
+	Email string   `jsonapi:"attr,email" validate:"email"`
+	Site  string   `jsonapi:"attr,site" validate:"url"`
+	Ref   *string  `jsonapi:"attr,ref" validate:"required,uuid"`
+	Tags  []string `jsonapi:"attr,tags" validate:"max=2"`
+}
+
+func errorPointers(err error) []string {
+	res := []string{}
+	if errs, ok := err.(Errors); ok {
+		for _, e := range errs.Errors {
+			res = append(res, e.Source.Pointer+" "+e.Code)
+		}
+	}
+	return res
+}
+
+func TestValidateTags(t *testing.T) {
+	ref := "0f8fad5b-d9cb-469f-a165-70867728950e"
+	s := testValidated{Name: "John", Age: 20, Code: "AB", Role: "user", Email: "a@b.c", Site: "http://a.b/c", Ref: &ref, Tags: []string{"a"}}
+	assertNil(t, Validate(&s))
+
+	bad := "x"
+	s = testValidated{Name: "Jo", Age: 17, Code: "a", Role: "guest", Email: "a", Site: "/c", Ref: &bad, Tags: []string{"a", "b", "c"}}
+	want := []string{
+		"/data/attributes/name min_length",
+		"/data/attributes/age min_value",
+		"/data/attributes/code length",
+		"/data/attributes/code invalid_format",
+		"/data/attributes/role one_of",
+		"/data/attributes/email email",
+		"/data/attributes/site url",
+		"/data/attributes/ref uuid",
+		"/data/attributes/tags max_items",
+	}
+	assertEqual(t, want, errorPointers(Validate(&s)))
+
+	s = testValidated{Role: "user"}
+	want = []string{
+		"/data/attributes/name required",
+		"/data/attributes/age min_value",
+		"/data/attributes/code length",
+		"/data/attributes/ref required",
+	}
+	assertEqual(t, want, errorPointers(Validate(&s)))
+}
+
+func TestUnmarshalValidateTags(t *testing.T) {
+	req := `{"data":{"id":"1","type":"test-validated","attributes":{"name":"Jo","age":18,"code":"AB","role":"admin","ref":"0f8fad5b-d9cb-469f-a165-70867728950e"}}}`
+	s := testValidated{}
+	err := Unmarshal([]byte(req), &s)
+	assertEqual(t, []string{"/data/attributes/name min_length"}, errorPointers(err))
+	assertEqual(t, "min length is3", err.Error())
+}
+
+type testOperation struct {
+	ID    uint64 `jsonapi:"id,test-operations"`
+	Name  string `jsonapi:"attr,name" validate:"required,min=3"`
+	Email string `jsonapi:"attr,email" validate:"required,email"`
+	Age   int    `jsonapi:"attr,age" validate:"min=18"`
+	Title string `jsonapi:"attr,title"`
+}
+
+func (t *testOperation) ValidateJSONAPI(v *Validator) {
+	v.Present(t.Title, "title")
+}
+
+func TestUnmarshalOperations(t *testing.T) {
+	req := []byte(`{"data":{"type":"test-operations","attributes":{"name":"Jo"}}}`)
+
+	s := testOperation{}
+	_, err := UnmarshalWithOptions(req, &s, UnmarshalOptions{Operation: OperationCreate})
+	want := []string{
+		"/data/attributes/name min_length",
+		"/data/attributes/email required",
+		"/data/attributes/title required",
+	}
+	assertEqual(t, want, errorPointers(err))
+
+	s = testOperation{Name: "John", Email: "j@a.b", Age: 10}
+	_, err = UnmarshalWithOptions(req, &s, UnmarshalOptions{Operation: OperationUpdate})
+	assertEqual(t, []string{"/data/attributes/name min_length"}, errorPointers(err))
+
+	req = []byte(`{"data":{"id":"1","type":"test-operations","attributes":{"email":"","title":"T"}}}`)
+	s = testOperation{Name: "John", Email: "j@a.b", Age: 10}
+	changes, err := UnmarshalWithOptions(req, &s, UnmarshalOptions{Operation: OperationUpdate})
+	assertEqual(t, []string{"/data/attributes/email required"}, errorPointers(err))
+	assertEqual(t, true, changes.Contains("email", "title"))
+
+	s = testOperation{}
+	err = Unmarshal(req, &s)
+	want = []string{
+		"/data/attributes/name required",
+		"/data/attributes/email required",
+		"/data/attributes/age min_value",
+	}
+	assertEqual(t, want, errorPointers(err))
+}
+
+type testOperationTask struct {
+	ID    uint64         `jsonapi:"id,test-operation-tasks"`
+	Title string         `jsonapi:"attr,title" validate:"required"`
+	Owner *testOperation `jsonapi:"rel,owner"`
+}
+
+func TestUnmarshalOperationsIncluded(t *testing.T) {
+	req := []byte(`{"data":{"id":"1","type":"test-operation-tasks","attributes":{"title":"T"},` +
+		`"relationships":{"owner":{"data":{"type":"test-operations","id":"2"}}}},` +
+		`"included":[{"type":"test-operations","id":"2","attributes":{"name":"Jo","title":"T"}}]}`)
+
+	s := testOperationTask{}
+	_, err := UnmarshalWithOptions(req, &s, UnmarshalOptions{Operation: OperationUpdate})
+	assertEqual(t, []string{"/included/0/attributes/name min_length"}, errorPointers(err))
+
+	s = testOperationTask{}
+	_, err = UnmarshalWithOptions(req, &s, UnmarshalOptions{Operation: OperationCreate})
+	want := []string{
+		"/included/0/attributes/name min_length",
+		"/included/0/attributes/email required",
+	}
+	assertEqual(t, want, errorPointers(err))
+}
+
+type testPeriod struct {
+	From string `json:"from"`
+	To   string `json:"to" validate:"gtfield=from"`
+}
+
+type testCrossField struct {
+	ID           uint64     `jsonapi:"id,test-cross"`
+	Password     string     `jsonapi:"attr,password"`
+	Confirmation string     `jsonapi:"attr,confirmation" validate:"eqfield=password"`
+	StartsAt     time.Time  `jsonapi:"attr,starts-at"`
+	EndsAt       time.Time  `jsonapi:"attr,ends-at" validate:"gtfield=starts-at"`
+	Count        int        `jsonapi:"attr,count" validate:"test-even"`
+	Period       testPeriod `jsonapi:"attr,period"`
+}
+
+func TestCustomRules(t *testing.T) {
+	RegisterRule("test-even", func(v *Validator, c RuleContext) {
+		if c.Value.(int)%2 != 0 {
+			v.Invalid(c.Pointer, "even", "must be even")
+		}
+	})
+	RegisterStructValidator(&testCrossField{}, func(v *Validator, i interface{}) {
+		if i.(*testCrossField).Password == "secret" {
+			v.Invalid("password", "weak", "too weak")
+			v.Invalid("confirmation", "weak", "too weak")
+		}
+	})
+	RegisterStructValidator(testPeriod{}, func(v *Validator, i interface{}) {
+		if i.(*testPeriod).From == "" {
+			v.Invalid("from", CodeRequired, "from required")
+		}
+	})
+
+	now := time.Now()
+	s := testCrossField{Password: "a", Confirmation: "a", StartsAt: now, EndsAt: now.Add(time.Hour), Count: 2, Period: testPeriod{From: "a", To: "b"}}
+	assertNil(t, Validate(&s))
+
+	s = testCrossField{Password: "secret", Confirmation: "b", StartsAt: now, EndsAt: now, Count: 3, Period: testPeriod{To: "b"}}
+	want := []string{
+		"/data/attributes/confirmation equal_field",
+		"/data/attributes/ends-at greater_field",
+		"/data/attributes/count even",
+		"/data/attributes/period/from required",
+		"/data/attributes/password weak",
+		"/data/attributes/confirmation weak",
+	}
+	assertEqual(t, want, errorPointers(Validate(&s)))
+	assertEqual(t, want, errorPointers(Validate(s)))
+}
+
+type testNilCrossField struct {
+	ID           uint64  `jsonapi:"id,test-nil-cross"`
+	Password     *string `jsonapi:"attr,password"`
+	Confirmation *string `jsonapi:"attr,confirmation" validate:"eqfield=password"`
+}
+
+func TestEqFieldNil(t *testing.T) {
+	a, b := "a", "a"
+	assertNil(t, Validate(&testNilCrossField{}))
+	assertNil(t, Validate(&testNilCrossField{Password: &a}))
+	assertNil(t, Validate(&testNilCrossField{Password: &a, Confirmation: &b}))
+	assertEqual(t, []string{"/data/attributes/confirmation equal_field"}, errorPointers(Validate(&testNilCrossField{Confirmation: &b})))
+}
@@ -0,0 +1,492 @@
+package jsonapi
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Schema is JSON Schema or OpenAPI object
+type Schema map[string]interface{}
+
+var (
+	linkType     = reflect.TypeOf(Link{})
+	linksType    = reflect.TypeOf(Links{})
+	errorsType   = reflect.TypeOf(Errors{})
+	errLinksType = reflect.TypeOf(ErrorLinks{})
+)
+
+// ResourceSchema returns JSON Schema of resource object produced by Marshal with scope.
+// Nil scope selects all fields. Invalid tags of resource or nested structures are returned as error.
+// 	s, err := jsonapi.ResourceSchema(&Post{}, nil)
+func ResourceSchema(i interface{}, scope *Scope) (Schema, error) {
+	t, f, err := schemaType(i)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := attributesSchema(t, f.attrs, scope, false)
+	if err != nil {
+		return nil, err
+	}
+	props := Schema{
+		"type":       Schema{"type": "string", "const": f.stype},
+		"id":         Schema{"type": "string"},
+		"attributes": attrs,
+	}
+	if len(f.rels) > 0 {
+		props["relationships"] = relationshipsSchema(t, f.rels, scope, false)
+	}
+	if len(f.links) > 0 {
+		props["links"] = linksSchema(f.links)
+	}
+	if len(f.meta) > 0 {
+		if props["meta"], err = attributesSchema(t, f.meta, scope, false); err != nil {
+			return nil, err
+		}
+	}
+	return Schema{
+		"type":       "object",
+		"properties": props,
+		"required":   []string{"type", "id", "attributes"},
+	}, nil
+}
+
+// RequestSchema returns JSON Schema of request document accepted by Unmarshal with scope.
+// Readonly attributes are omitted and attributes with required rule are required.
+// 	s, err := jsonapi.RequestSchema(&Post{}, &jsonapi.Scope{Roles: []string{"owner"}})
+func RequestSchema(i interface{}, scope *Scope) (Schema, error) {
+	t, f, err := schemaType(i)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := attributesSchema(t, f.attrs, scope, true)
+	if err != nil {
+		return nil, err
+	}
+	props := Schema{
+		"type":       Schema{"type": "string", "const": f.stype},
+		"id":         Schema{"type": "string"},
+		"attributes": attrs,
+	}
+	if len(f.rels) > 0 {
+		props["relationships"] = relationshipsSchema(t, f.rels, scope, true)
+	}
+	if len(f.meta) > 0 {
+		if props["meta"], err = attributesSchema(t, f.meta, scope, true); err != nil {
+			return nil, err
+		}
+	}
+	return Schema{
+		"type": "object",
+		"properties": Schema{
+			"data": Schema{
+				"type":       "object",
+				"properties": props,
+				"required":   []string{"type"},
+			},
+			"included": Schema{"type": "array", "items": Schema{"type": "object"}},
+		},
+		"required": []string{"data"},
+	}, nil
+}
+
+// ErrorsSchema returns JSON Schema of errors document
+func ErrorsSchema() Schema {
+	// Errors has no validate tags, so schema can't fail
+	s, _ := typeSchema(errorsType, map[reflect.Type]bool{})
+	return s
+}
+
+// OpenAPIComponents returns OpenAPI 3.1 components object with schemas
+// of resources, request and response documents for every item and errors document.
+// Schemas are named after resource type:
+// 	posts, posts-request, posts-document, posts-collection, errors
+// 	c, err := jsonapi.OpenAPIComponents(nil, &Post{}, &Comment{})
+func OpenAPIComponents(scope *Scope, items ...interface{}) (Schema, error) {
+	schemas := Schema{"errors": ErrorsSchema()}
+	for _, i := range items {
+		_, f, err := schemaType(i)
+		if err != nil {
+			return nil, err
+		}
+		ref := Schema{"$ref": "#/components/schemas/" + f.stype}
+		if schemas[f.stype], err = ResourceSchema(i, scope); err != nil {
+			return nil, err
+		}
+		if schemas[f.stype+"-request"], err = RequestSchema(i, scope); err != nil {
+			return nil, err
+		}
+		schemas[f.stype+"-document"] = documentSchema(ref)
+		schemas[f.stype+"-collection"] = documentSchema(Schema{"type": "array", "items": ref})
+	}
+	return Schema{"schemas": schemas}, nil
+}
+
+// documentSchema returns response document schema with data
+func documentSchema(data Schema) Schema {
+	return Schema{
+		"type": "object",
+		"properties": Schema{
+			"data":     data,
+			"included": Schema{"type": "array", "items": Schema{"type": "object"}},
+			"links":    Schema{"type": "object", "additionalProperties": linkSchema()},
+			"meta":     Schema{"type": "object"},
+		},
+		"required": []string{"data"},
+	}
+}
+
+func schemaType(i interface{}) (reflect.Type, *fields, error) {
+	t := reflect.TypeOf(i)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("jsonapi: can't build schema for %T, struct expected", i)
+	}
+	f := types.get(t)
+	return t, f, f.err
+}
+
+// attributesSchema returns object schema of attribute or meta fields.
+// Request schema skips readonly fields and requires fields with required rule,
+// response schema requires fields without omitempty option.
+func attributesSchema(t reflect.Type, flds []field, scope *Scope, request bool) (Schema, error) {
+	props := Schema{}
+	required := []string{}
+	for _, f := range flds {
+		if !f.allowed(direction(request), schemaScope(scope)) || (request && f.readonly) {
+			continue
+		}
+		ft := t.FieldByIndex(f.idx).Type
+		var s Schema
+		switch {
+		case f.quote:
+			s = Schema{"type": "string"}
+		case f.codec != nil && f.codec.Schema != nil:
+			s = Schema{}
+			for k, v := range f.codec.Schema {
+				s[k] = v
+			}
+		case f.codec != nil:
+			s = Schema{}
+		default:
+			var err error
+			if s, err = typeSchema(ft, map[reflect.Type]bool{t: true}); err != nil {
+				return nil, err
+			}
+		}
+		if ft.Kind() == reflect.Ptr && !f.skipEmpty {
+			s = nullable(s)
+		}
+		rulesSchema(s, f.rules)
+		if f.readonly {
+			s["readOnly"] = true
+		}
+		props[f.name] = s
+
+		if request && hasRule(f.rules, "required") || !request && !f.skipEmpty {
+			required = append(required, f.name)
+		}
+	}
+	s := Schema{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s, nil
+}
+
+// relationshipsSchema returns relationships object schema
+func relationshipsSchema(t reflect.Type, flds []field, scope *Scope, request bool) Schema {
+	props := Schema{}
+	for _, f := range flds {
+		if !f.allowed(direction(request), schemaScope(scope)) {
+			continue
+		}
+		ft := t.FieldByIndex(f.idx).Type
+		props[f.name] = Schema{
+			"type": "object",
+			"properties": Schema{
+				"links": Schema{
+					"type":       "object",
+					"properties": Schema{"self": linkSchema(), "related": linkSchema()},
+				},
+				"data": linkageSchema(ft),
+				"meta": Schema{"type": "object"},
+			},
+		}
+	}
+	return Schema{"type": "object", "properties": props}
+}
+
+// linkageSchema returns resource linkage schema for relationship field type
+func linkageSchema(t reflect.Type) Schema {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return Schema{"type": "array", "items": identifierSchema(t.Elem())}
+		}
+	case reflect.Ptr, reflect.Interface:
+		return nullable(identifierSchema(t))
+	}
+	if t == relationType {
+		return Schema{"oneOf": []interface{}{
+			nullable(identifierSchema(t)),
+			Schema{"type": "array", "items": identifierSchema(t)},
+		}}
+	}
+	return identifierSchema(t)
+}
+
+// identifierSchema returns resource identifier schema with type constant if type is known
+func identifierSchema(t reflect.Type) Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	stype := Schema{"type": "string"}
+	if t.Kind() == reflect.Struct && t != relationType {
+		if f := types.get(t); len(f.id) > 0 {
+			stype["const"] = f.stype
+		}
+	}
+	return Schema{
+		"type": "object",
+		"properties": Schema{
+			"type": stype,
+			"id":   Schema{"type": "string"},
+		},
+		"required": []string{"type", "id"},
+	}
+}
+
+// linksSchema returns links object schema of link fields
+func linksSchema(flds []field) Schema {
+	props := Schema{"self": linkSchema()}
+	for _, f := range flds {
+		props[f.name] = linkSchema()
+	}
+	return Schema{"type": "object", "properties": props}
+}
+
+// linkSchema returns schema of link string or link object
+func linkSchema() Schema {
+	return Schema{"oneOf": []interface{}{
+		Schema{"type": "string", "format": "uri-reference"},
+		Schema{
+			"type": "object",
+			"properties": Schema{
+				"href":        Schema{"type": "string", "format": "uri-reference"},
+				"rel":         Schema{"type": "string"},
+				"describedby": Schema{"type": "string", "format": "uri-reference"},
+				"title":       Schema{"type": "string"},
+				"type":        Schema{"type": "string"},
+				"hreflang": Schema{"oneOf": []interface{}{
+					Schema{"type": "string"},
+					Schema{"type": "array", "items": Schema{"type": "string"}},
+				}},
+				"meta": Schema{"type": "object"},
+			},
+			"required": []string{"href"},
+		},
+		Schema{"type": "null"},
+	}}
+}
+
+// typeSchema returns schema of value marshalled with encoding/json.
+// Recursive structures and custom marshallers produce empty schema.
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) (Schema, error) {
+	switch t {
+	case timeType:
+		return Schema{"type": "string", "format": "date-time"}, nil
+	case linkType:
+		return linkSchema(), nil
+	case linksType:
+		return Schema{"type": "object", "properties": Schema{"self": linkSchema(), "related": linkSchema()}}, nil
+	case errLinksType:
+		return Schema{"type": "object", "properties": Schema{"about": linkSchema(), "type": linkSchema()}}, nil
+	}
+	if t.Kind() != reflect.Ptr && t.Implements(jsonMarshallerType) {
+		return Schema{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}, nil
+	case reflect.String:
+		return Schema{"type": "string"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Schema{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Schema{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}, nil
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), seen)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return Schema{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := typeSchema(t.Elem(), seen)
+		return Schema{"type": "array", "items": items}, err
+	case reflect.Map:
+		items, err := typeSchema(t.Elem(), seen)
+		return Schema{"type": "object", "additionalProperties": items}, err
+	case reflect.Struct:
+		if seen[t] {
+			return Schema{}, nil
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		return structSchema(t, seen)
+	}
+	return Schema{}, nil
+}
+
+// structSchema returns object schema of structure using json tags
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) (Schema, error) {
+	props := Schema{}
+	required := []string{}
+	for _, idx := range typeFields(t, []int{}) {
+		fd := t.FieldByIndex(idx)
+		name, opts := fd.Name, ""
+		if tag := fd.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if i := strings.IndexByte(tag, ','); i >= 0 {
+				tag, opts = tag[:i], tag[i:]
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		omit := strings.Contains(opts, ",omitempty")
+
+		s, err := typeSchema(fd.Type, seen)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(opts, ",string") {
+			s = Schema{"type": "string"}
+		}
+		if fd.Type.Kind() == reflect.Ptr && !omit {
+			s = nullable(s)
+		}
+		rules, err := parseRules(fd)
+		if err != nil {
+			return nil, fmt.Errorf("jsonapi: invalid tag of %s.%s: %v", t, fd.Name, err)
+		}
+		rulesSchema(s, rules)
+		props[name] = s
+		if !omit {
+			required = append(required, name)
+		}
+	}
+	s := Schema{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s, nil
+}
+
+// nullable allows null value for schema
+func nullable(s Schema) Schema {
+	if typ, ok := s["type"].(string); ok {
+		s["type"] = []string{typ, "null"}
+		return s
+	}
+	if len(s) == 0 {
+		return s
+	}
+	return Schema{"oneOf": []interface{}{s, Schema{"type": "null"}}}
+}
+
+// rulesSchema adds keywords of validation rules to schema
+func rulesSchema(s Schema, rules []rule) {
+	typ, _ := s["type"].(string)
+	if types, ok := s["type"].([]string); ok {
+		typ = types[0]
+	}
+	for _, r := range rules {
+		switch r.name {
+		case "email":
+			s["format"] = "email"
+		case "url":
+			s["format"] = "uri"
+		case "uuid":
+			s["format"] = "uuid"
+		case "format":
+			s["pattern"] = r.arg
+		case "oneof":
+			s["enum"] = enumValues(typ, r.set)
+		case "min", "max", "len":
+			for _, k := range sizeKeywords(typ, r.name) {
+				s[k] = r.num
+			}
+		}
+	}
+}
+
+// enumValues converts oneof rule values to JSON type of schema
+func enumValues(typ string, set []string) []interface{} {
+	res := make([]interface{}, 0, len(set))
+	for _, v := range set {
+		var item interface{}
+		var err error
+		switch typ {
+		case "integer":
+			item, err = strconv.ParseInt(v, 10, 64)
+		case "number":
+			item, err = strconv.ParseFloat(v, 64)
+		case "boolean":
+			item, err = strconv.ParseBool(v)
+		default:
+			item = v
+		}
+		if err != nil {
+			item = v
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+// sizeKeywords returns schema keywords for min, max and len rules
+func sizeKeywords(typ, name string) []string {
+	prefix := map[string]string{"string": "Length", "array": "Items", "object": "Properties"}[typ]
+	switch {
+	case prefix != "" && name == "len":
+		return []string{"min" + prefix, "max" + prefix}
+	case prefix != "":
+		return []string{name + prefix}
+	case typ == "integer" || typ == "number":
+		return map[string][]string{"min": {"minimum"}, "max": {"maximum"}}[name]
+	}
+	return nil
+}
+
+// schemaScope returns scope or scope selecting all fields if scope is nil
+func schemaScope(scope *Scope) Scope {
+	if scope == nil {
+		return Scope{}
+	}
+	return *scope
+}
+
+// direction returns write access for request schema and read access otherwise
+func direction(request bool) access {
+	if request {
+		return accessWrite
+	}
+	return accessRead
+}
+
+func hasRule(rules []rule, name string) bool {
+	for _, r := range rules {
+		if r.name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+)
+
+type testSchemaUser struct {
+	ID        uint64      `jsonapi:"id,users"`
+	Email     string      `jsonapi:"attr,email" validate:"required,email"`
+	Role      string      `jsonapi:"attr,role,omitempty" validate:"oneof=admin user"`
+	Age       int         `jsonapi:"attr,age,omitempty" validate:"min=18"`
+	Level     int         `jsonapi:"attr,level,omitempty" validate:"oneof=1 2 3"`
+	Code      uint64      `jsonapi:"attr,code,readonly,string"`
+	Created   time.Time   `jsonapi:"attr,created,readonly"`
+	Deleted   *time.Time  `jsonapi:"attr,deleted"`
+	Secret    string      `jsonapi:"attr,secret" scope:"admin"`
+	Address   testSub     `jsonapi:"attr,address,omitempty"`
+	Self      Link        `jsonapi:"link,self"`
+	Posts     []testPost  `jsonapi:"rel,posts"`
+	Favourite interface{} `jsonapi:"rel,favourite"`
+	Version   int         `jsonapi:"meta,version"`
+}
+
+func schemaJSON(t *testing.T, s interface{}) string {
+	b, err := json.Marshal(s)
+	assertNil(t, err)
+	return string(b)
+}
+
+func TestResourceSchema(t *testing.T) {
+	s, err := ResourceSchema(&testSchemaUser{}, nil)
+	assertNil(t, err)
+	props := s["properties"].(Schema)
+	attrs := props["attributes"].(Schema)
+
+	assertEqual(t, `{"const":"users","type":"string"}`, schemaJSON(t, props["type"]))
+	assertEqual(t, []string{"email", "code", "created", "deleted", "secret"}, attrs["required"])
+	ap := attrs["properties"].(Schema)
+	assertEqual(t, `{"format":"email","type":"string"}`, schemaJSON(t, ap["email"]))
+	assertEqual(t, `{"enum":["admin","user"],"type":"string"}`, schemaJSON(t, ap["role"]))
+	assertEqual(t, `{"minimum":18,"type":"integer"}`, schemaJSON(t, ap["age"]))
+	assertEqual(t, `{"enum":[1,2,3],"type":"integer"}`, schemaJSON(t, ap["level"]))
+	assertEqual(t, `{"readOnly":true,"type":"string"}`, schemaJSON(t, ap["code"]))
+	assertEqual(t, `{"format":"date-time","type":["string","null"]}`, schemaJSON(t, ap["deleted"]))
+	assertEqual(t, `{"properties":{"city":{"type":"string"},"country":{"type":"string"}},"required":["country","city"],"type":"object"}`, schemaJSON(t, ap["address"]))
+
+	rels := props["relationships"].(Schema)["properties"].(Schema)
+	posts := rels["posts"].(Schema)["properties"].(Schema)["data"].(Schema)
+	assertEqual(t, `{"items":{"properties":{"id":{"type":"string"},"type":{"const":"posts","type":"string"}},"required":["type","id"],"type":"object"},"type":"array"}`, schemaJSON(t, posts))
+	fav := rels["favourite"].(Schema)["properties"].(Schema)["data"].(Schema)
+	assertEqual(t, `{"properties":{"id":{"type":"string"},"type":{"type":"string"}},"required":["type","id"],"type":["object","null"]}`, schemaJSON(t, fav))
+	assertEqual(t, `{"properties":{"version":{"type":"integer"}},"required":["version"],"type":"object"}`, schemaJSON(t, props["meta"]))
+
+	public := Roles("public")
+	s, err = ResourceSchema(&testSchemaUser{}, &public)
+	assertNil(t, err)
+	ap = s["properties"].(Schema)["attributes"].(Schema)["properties"].(Schema)
+	_, ok := ap["secret"]
+	assertEqual(t, false, ok)
+}
+
+func TestRequestSchema(t *testing.T) {
+	s, err := RequestSchema(testSchemaUser{}, nil)
+	assertNil(t, err)
+	data := s["properties"].(Schema)["data"].(Schema)
+	attrs := data["properties"].(Schema)["attributes"].(Schema)
+	assertEqual(t, []string{"email"}, attrs["required"])
+	ap := attrs["properties"].(Schema)
+	_, ok := ap["code"]
+	assertEqual(t, false, ok)
+	_, ok = ap["created"]
+	assertEqual(t, false, ok)
+}
+
+func TestOpenAPIComponents(t *testing.T) {
+	c, err := OpenAPIComponents(nil, &testSchemaUser{})
+	assertNil(t, err)
+	schemas := c["schemas"].(Schema)
+	for _, name := range []string{"users", "users-request", "users-document", "users-collection", "errors"} {
+		_, ok := schemas[name]
+		assertEqual(t, true, ok)
+	}
+	doc := schemas["users-collection"].(Schema)["properties"].(Schema)["data"]
+	assertEqual(t, `{"items":{"$ref":"#/components/schemas/users"},"type":"array"}`, schemaJSON(t, doc))
+
+	errs := schemas["errors"].(Schema)["properties"].(Schema)["errors"].(Schema)["items"].(Schema)
+	ep := errs["properties"].(Schema)
+	assertEqual(t, `{"type":"string"}`, schemaJSON(t, ep["status"]))
+	assertEqual(t, []string{"about", "type"}, keys(ep["links"].(Schema)["properties"].(Schema)))
+	assertEqual(t, `{"properties":{"header":{"type":"string"},"parameter":{"type":"string"},"pointer":{"type":"string"}},"type":"object"}`, schemaJSON(t, ep["source"]))
+}
+
+func TestSchemaInvalidTags(t *testing.T) {
+	_, err := ResourceSchema(&struct {
+		ID   uint64 `jsonapi:"id,rules"`
+		Name string `jsonapi:"attr,name" validate:"requird"`
+	}{}, nil)
+	assertEqual(t, true, err != nil)
+
+	_, err = RequestSchema(&struct {
+		ID      uint64          `jsonapi:"id,nested"`
+		Address testCacheNested `jsonapi:"attr,address"`
+	}{}, nil)
+	assertEqual(t, `jsonapi: invalid tag of jsonapi.testCacheNested.City: unknown validation rule 'requird'`, err.Error())
+
+	_, err = OpenAPIComponents(nil, "users")
+	assertEqual(t, "jsonapi: can't build schema for string, struct expected", err.Error())
+}
+
+func keys(s Schema) []string {
+	res := []string{}
+	for k := range s {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,112 @@
+package jsonapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scope is set of caller roles used for selecting attributes, meta and relationships
+// on marshal and unmarshal. Fields are selected with scope tag which may differ
+// for reading (marshal) and writing (unmarshal):
+// 	Name   string `jsonapi:"attr,name" scope:"admin,owner"`              // read and write
+// 	Salary int    `jsonapi:"attr,salary" scope:"read:admin,owner write:admin"`
+// 	Bio    string `jsonapi:"attr,bio" scope:"read:* write:owner"`        // * allows any caller
+//
+// Scope of one direction is used for both unless the other direction is set.
+// Fields without scope tag are selected for every caller unless Strict is set.
+// Scoped fields are selected for every caller if Roles is empty and Strict is not set.
+type Scope struct {
+	Roles []string
+	// Strict denies fields without scope tag
+	Strict bool
+}
+
+// Roles returns scope for caller roles
+// 	b, err := jsonapi.MarshalScope(user, jsonapi.Roles("owner", "admin"))
+func Roles(roles ...string) Scope {
+	return Scope{Roles: roles}
+}
+
+// scopeOf converts single scope string into Scope. Empty string selects all fields.
+func scopeOf(s string) Scope {
+	if s == "" {
+		return Scope{}
+	}
+	return Scope{Roles: []string{s}}
+}
+
+// has returns true if scope has any of roles
+func (s Scope) has(roles []string) bool {
+	for _, r := range roles {
+		if r == "*" {
+			return true
+		}
+		for _, v := range s.Roles {
+			if v == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// access is direction of field usage
+type access int
+
+const (
+	accessRead access = iota
+	accessWrite
+)
+
+// fieldScope is parsed scope tag
+type fieldScope struct {
+	read, write       []string
+	readSet, writeSet bool
+}
+
+// parseScope parses scope tag. Groups are separated by space,
+// roles of group without read: or write: prefix are used for both directions,
+// as well as roles of the only direction set.
+// Unknown prefix is error so misspelled scope never leaves field unrestricted.
+func parseScope(tag string) (fieldScope, error) {
+	s := fieldScope{}
+	for _, group := range strings.Fields(tag) {
+		dir := ""
+		if i := strings.IndexByte(group, ':'); i >= 0 {
+			dir, group = group[:i], group[i+1:]
+		}
+		if dir != "" && dir != "read" && dir != "write" {
+			return s, fmt.Errorf("unknown scope direction %q", dir)
+		}
+		roles := strings.Split(group, ",")
+		if dir == "" || dir == "read" {
+			s.read, s.readSet = append(s.read, roles...), true
+		}
+		if dir == "" || dir == "write" {
+			s.write, s.writeSet = append(s.write, roles...), true
+		}
+	}
+	// scope of one direction restricts the other one too unless it is set
+	switch {
+	case s.readSet && !s.writeSet:
+		s.write, s.writeSet = s.read, true
+	case s.writeSet && !s.readSet:
+		s.read, s.readSet = s.write, true
+	}
+	return s, nil
+}
+
+// allowed returns true if field is selected for direction and scope
+func (s fieldScope) allowed(a access, scope Scope) bool {
+	roles, set := s.read, s.readSet
+	if a == accessWrite {
+		roles, set = s.write, s.writeSet
+	}
+	switch {
+	case !set:
+		return !scope.Strict
+	case len(scope.Roles) == 0 && !scope.Strict:
+		return true
+	}
+	return scope.has(roles)
+}
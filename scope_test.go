@@ -0,0 +1,142 @@
+package jsonapi
+
+import (
+	"context"
+	"testing"
+)
+
+type testScoped struct {
+	ID      uint64    `jsonapi:"id,scoped"`
+	Name    string    `jsonapi:"attr,name"`
+	Email   string    `jsonapi:"attr,email" scope:"admin,owner"`
+	Salary  int       `jsonapi:"attr,salary" scope:"read:admin,owner write:admin"`
+	Bio     string    `jsonapi:"attr,bio" scope:"read:* write:owner"`
+	Manager *testPost `jsonapi:"rel,manager" scope:"admin"`
+}
+
+func TestMarshalScope(t *testing.T) {
+	s := testScoped{ID: 1, Name: "n", Email: "e", Salary: 10, Bio: "b"}
+
+	res, err := MarshalScope(&s, Roles())
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"scoped","attributes":{"name":"n","email":"e","salary":10,"bio":"b"},"relationships":{"manager":{"data":null}}}`, string(res))
+
+	res, err = MarshalScope(&s, Roles("owner", "guest"))
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"scoped","attributes":{"name":"n","email":"e","salary":10,"bio":"b"}}`, string(res))
+
+	res, err = MarshalScope(&s, Scope{Roles: []string{"guest"}, Strict: true})
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"scoped","attributes":{"bio":"b"}}`, string(res))
+
+	res, err = MarshalScope(&s, Scope{Strict: true})
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"scoped","attributes":{"bio":"b"}}`, string(res))
+}
+
+func TestUnmarshalScope(t *testing.T) {
+	b := []byte(`{"data":{"type":"scoped","attributes":{"name":"n","email":"e","salary":10,"bio":"b"}}}`)
+
+	s := testScoped{}
+	assertNil(t, UnmarshalScope(b, &s, Roles("owner")))
+	assertEqual(t, testScoped{Name: "n", Email: "e", Bio: "b"}, s)
+
+	s = testScoped{}
+	assertNil(t, UnmarshalScope(b, &s, Scope{Roles: []string{"admin"}, Strict: true}))
+	assertEqual(t, testScoped{Email: "e", Salary: 10}, s)
+
+	s = testScoped{}
+	_, err := UnmarshalWithOptions(b, &s, UnmarshalOptions{Scope: Roles("guest"), Operation: OperationUpdate})
+	assertNil(t, err)
+	assertEqual(t, testScoped{Name: "n"}, s)
+}
+
+type testReadScoped struct {
+	ID     uint64 `jsonapi:"id,read-scoped"`
+	Name   string `jsonapi:"attr,name"`
+	Salary int    `jsonapi:"attr,salary" scope:"read:admin"`
+}
+
+func TestOneDirectionScope(t *testing.T) {
+	b := []byte(`{"data":{"type":"read-scoped","attributes":{"name":"n","salary":10}}}`)
+
+	s := testReadScoped{}
+	assertNil(t, UnmarshalScope(b, &s, Roles("guest")))
+	assertEqual(t, testReadScoped{Name: "n"}, s)
+
+	assertNil(t, UnmarshalScope(b, &s, Roles("admin")))
+	assertEqual(t, testReadScoped{Name: "n", Salary: 10}, s)
+
+	res, err := MarshalScope(&testReadScoped{ID: 1, Name: "n", Salary: 10}, Roles("guest"))
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"read-scoped","attributes":{"name":"n"}}`, string(res))
+}
+
+func TestResponseRoles(t *testing.T) {
+	s := testScoped{ID: 1, Name: "n", Email: "e"}
+	res, err := (&Response{Data: &s, Roles: &Scope{Roles: []string{"owner"}, Strict: true}}).MarshalJSON()
+	assertNil(t, err)
+	assertEqual(t, `{"data":{"id":"1","type":"scoped","attributes":{"email":"e","salary":0,"bio":""}}}`, string(res))
+}
+
+type testMisspelledScope struct {
+	ID     uint64 `jsonapi:"id,misspelled"`
+	Name   string `jsonapi:"attr,name"`
+	Salary int    `jsonapi:"attr,salary" scope:"raed:admin"`
+}
+
+func TestInvalidScope(t *testing.T) {
+	s := testMisspelledScope{ID: 1, Name: "n", Salary: 10}
+	_, err := MarshalScope(&s, Roles("user"))
+	assertEqual(t, `jsonapi: invalid tag of jsonapi.testMisspelledScope.Salary: unknown scope direction "raed"`, err.Error())
+
+	err = UnmarshalScope([]byte(`{"data":{"type":"misspelled","attributes":{"salary":20}}}`), &s, Roles("user"))
+	assertEqual(t, `jsonapi: invalid tag of jsonapi.testMisspelledScope.Salary: unknown scope direction "raed"`, err.Error())
+	assertEqual(t, 10, s.Salary)
+
+	_, err = parseScope("read:admin wrte:admin")
+	assertEqual(t, `unknown scope direction "wrte"`, err.Error())
+}
+
+type testCtxKey struct{}
+
+type testOwned struct {
+	ID    uint64    `jsonapi:"id,owned"`
+	Name  string    `jsonapi:"attr,name"`
+	Email string    `jsonapi:"attr,email"`
+	Role  string    `jsonapi:"attr,role"`
+	Boss  *testPost `jsonapi:"rel,boss"`
+}
+
+func (o *testOwned) CanReadAttribute(ctx context.Context, name string) bool {
+	return name != "email" || ctx.Value(testCtxKey{}) == o.ID
+}
+
+func (o *testOwned) CanWriteAttribute(ctx context.Context, name string) bool {
+	return name != "role" && name != "boss" || ctx.Value(testCtxKey{}) == "admin"
+}
+
+func TestAttributeReader(t *testing.T) {
+	s := testOwned{ID: 1, Name: "n", Email: "e"}
+	res, err := MarshalContext(context.WithValue(context.Background(), testCtxKey{}, uint64(1)), &s, Scope{})
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"owned","attributes":{"name":"n","email":"e","role":""},"relationships":{"boss":{"data":null}}}`, string(res))
+
+	res, err = MarshalContext(context.WithValue(context.Background(), testCtxKey{}, uint64(2)), &s, Scope{})
+	assertNil(t, err)
+	assertEqual(t, `{"id":"1","type":"owned","attributes":{"name":"n","role":""},"relationships":{"boss":{"data":null}}}`, string(res))
+}
+
+func TestAttributeWriter(t *testing.T) {
+	b := []byte(`{"data":{"type":"owned","attributes":{"name":"n","role":"admin"},"relationships":{"boss":{"data":null}}}}`)
+
+	s := testOwned{}
+	err := UnmarshalContext(context.Background(), b, &s, Scope{})
+	assertEqual(t, []string{"/data/attributes/role forbidden", "/data/relationships/boss forbidden"}, errorPointers(err))
+	assertEqual(t, 403, err.(Errors).StatusCode())
+	assertEqual(t, testOwned{}, s)
+
+	err = UnmarshalContext(context.WithValue(context.Background(), testCtxKey{}, "admin"), b, &s, Scope{})
+	assertNil(t, err)
+	assertEqual(t, "admin", s.Role)
+}
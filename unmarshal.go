@@ -1,19 +1,25 @@
 package jsonapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 // Request structure for unmarshaling
 type Request struct {
 	Data struct {
-		ID         json.Number                `json:"id"`
-		Type       string                     `json:"type"`
-		Attributes map[string]json.RawMessage `json:"attributes"`
+		ID            json.Number                `json:"id"`
+		Type          string                     `json:"type"`
+		Attributes    map[string]json.RawMessage `json:"attributes"`
+		Relationships map[string]json.RawMessage `json:"relationships"`
+		Meta          map[string]json.RawMessage `json:"meta"`
+		Links         map[string]json.RawMessage `json:"links"`
 	} `json:"data"`
+	Included []json.RawMessage `json:"included"`
 }
 
 // Change structure for storing structure changes
@@ -34,43 +40,77 @@ func (c Change) equal() bool {
 
 // UnmarshalWithScope decoding json api compatible request
 func UnmarshalWithScope(b []byte, i interface{}, scope string) error {
-	return unmarshal(b, i, scope)
+	return UnmarshalContext(context.Background(), b, i, scopeOf(scope))
+}
+
+// UnmarshalScope decoding json api compatible request with fields writable by scope roles.
+// Attributes which are not writable are ignored.
+func UnmarshalScope(b []byte, i interface{}, scope Scope) error {
+	return UnmarshalContext(context.Background(), b, i, scope)
+}
+
+// UnmarshalContext decoding json api compatible request with fields writable by scope roles.
+// Context is passed to AttributeWriter and AfterUnmarshalerContext of every resource.
+// 	err := jsonapi.UnmarshalContext(r.Context(), body, &user, jsonapi.Roles("owner"))
+func UnmarshalContext(ctx context.Context, b []byte, i interface{}, scope Scope) error {
+	_, err := UnmarshalWithOptions(b, i, UnmarshalOptions{Context: ctx, Scope: scope})
+	return err
 }
 
 // Unmarshal decoding json api compatible request
 func Unmarshal(b []byte, i interface{}) error {
-	return unmarshal(b, i, "")
+	return UnmarshalContext(context.Background(), b, i, Scope{})
+}
+
+// UnmarshalOptions configures UnmarshalWithOptions. Zero value unmarshals like Unmarshal.
+type UnmarshalOptions struct {
+	// Context is passed to AttributeWriter and AfterUnmarshalerContext of every resource
+	Context context.Context
+	// Scope selects attributes, meta and relationships writable by caller roles
+	Scope Scope
+	// Operation selects validation rules. Changes are returned for OperationUpdate.
+	Operation Operation
 }
 
-// unmarshal decoding json api compatible request
-func unmarshal(b []byte, i interface{}, scope string) error {
+// UnmarshalWithOptions decoding json api compatible request with context,
+// caller scope and operation
+// 	changes, err := jsonapi.UnmarshalWithOptions(body, &post, jsonapi.UnmarshalOptions{
+// 		Context:   r.Context(),
+// 		Scope:     jsonapi.Roles("owner"),
+// 		Operation: jsonapi.OperationUpdate,
+// 	})
+func UnmarshalWithOptions(b []byte, i interface{}, opts UnmarshalOptions) (Changes, error) {
 	v := interfacePtr(i)
 	if !v.IsValid() {
-		return errMarshalInvalidData
+		return Changes{}, errMarshalInvalidData
 	}
-
-	d := decoder{}
-	return d.unmarshal(b, v, scope)
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	d := decoder{ctx: ctx, withChanges: opts.Operation == OperationUpdate, op: opts.Operation}
+	err := d.unmarshal(b, v, opts.Scope)
+	return d.changes, err
 }
 
 // UnmarshalWithChangesWithScope decoding json api compatible request into structure
 // and returning changes
 func UnmarshalWithChangesWithScope(b []byte, i interface{}, scope string) (Changes, error) {
-	return unmarshalWithChanges(b, i, scope)
+	return unmarshalWithChanges(b, i, scopeOf(scope))
 }
 
 // UnmarshalWithChanges decoding json api compatible request into structure
 // and returning changes
 func UnmarshalWithChanges(b []byte, i interface{}) (Changes, error) {
-	return unmarshalWithChanges(b, i, "")
+	return unmarshalWithChanges(b, i, Scope{})
 }
 
-func unmarshalWithChanges(b []byte, i interface{}, scope string) (Changes, error) {
+func unmarshalWithChanges(b []byte, i interface{}, scope Scope) (Changes, error) {
 	v := interfacePtr(i)
 	if !v.IsValid() {
 		return Changes{}, errMarshalInvalidData
 	}
-	d := decoder{withChanges: true}
+	d := decoder{ctx: context.Background(), withChanges: true}
 	err := d.unmarshal(b, v, scope)
 	return d.changes, err
 }
@@ -115,12 +155,16 @@ func (c Changes) Contains(keys ...string) bool {
 // }
 
 type decoder struct {
+	ctx         context.Context
 	withChanges bool
+	op          Operation
 	changes     Changes
+	included    map[string]*resource
+	resolved    map[string]reflect.Value
 }
 
 // Unmarshal decoding json api compatible request
-func (d *decoder) unmarshal(b []byte, e reflect.Value, scope string) error {
+func (d *decoder) unmarshal(b []byte, e reflect.Value, scope Scope) error {
 	t := e.Type()
 
 	if t.Implements(unmarshalerType) {
@@ -128,8 +172,34 @@ func (d *decoder) unmarshal(b []byte, e reflect.Value, scope string) error {
 		return m.UnmarshalJSONAPI(b)
 	}
 
+	req := Request{}
+	err := json.Unmarshal(b, &req)
+	if err != nil {
+		return err
+	}
+
+	if err := d.indexIncluded(req.Included); err != nil {
+		return err
+	}
+
+	res := resource{
+		resourceIdentifier: resourceIdentifier{Type: req.Data.Type, ID: req.Data.ID.String()},
+		Attributes:         req.Data.Attributes,
+		Relationships:      req.Data.Relationships,
+		Meta:               req.Data.Meta,
+		Links:              req.Data.Links,
+		pointer:            "/data",
+	}
+	return d.decode(e, &res, scope)
+}
+
+// decode sets structure fields from resource object
+func (d *decoder) decode(e reflect.Value, res *resource, scope Scope) error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
 	e1 := e
-	if t.Kind() == reflect.Ptr {
+	if e.Type().Kind() == reflect.Ptr {
 		e1 = e.Elem()
 	}
 
@@ -138,22 +208,31 @@ func (d *decoder) unmarshal(b []byte, e reflect.Value, scope string) error {
 		return errMarshalInvalidData
 	}
 
-	f := types.get(e1)
+	f := types.get(e1.Type())
+	if f.err != nil {
+		return f.err
+	}
 	if !f.api() {
 		return fmt.Errorf("jsonapi: %v incompatible with json api", t1.Name())
 	}
 
-	req := Request{}
-	err := json.Unmarshal(b, &req)
-	if err != nil {
-		return err
+	if res.Type != f.stype {
+		return fmt.Errorf("jsonapi: can't unmarshal item of type '%s' into item of type '%s'", res.Type, f.stype)
 	}
 
-	if req.Data.Type != f.stype {
-		return fmt.Errorf("jsonapi: can't unmarshal item of type '%s' into item of type '%s'", req.Data.Type, f.stype)
+	if e.Type().Implements(beforeUnmarshalerType) {
+		if err := e.Interface().(BeforeUnmarshaler).BeforeUnmarshalJSONAPI(); err != nil {
+			return err
+		}
+	}
+
+	if err := d.authorize(e, f, res, scope); err != nil {
+		return err
 	}
 
 	ne := reflect.New(t1).Elem()
+	set := make([][]int, 0, len(f.attrs)+len(f.rels))
+	submitted := make(map[string]bool, len(res.Attributes))
 
 	if d.withChanges {
 		d.changes = make([]Change, 0, len(f.attrs))
@@ -161,46 +240,172 @@ func (d *decoder) unmarshal(b []byte, e reflect.Value, scope string) error {
 
 	for _, attr := range f.attrs {
 		if !attr.readonly {
-			v, ok := req.Data.Attributes[attr.name]
+			v, ok := res.Attributes[attr.name]
 			if !ok {
 				continue
 			}
 
-			if !attr.inScope(scope) {
+			if !attr.allowed(accessWrite, scope) {
 				continue
 			}
 
-			curVal := e1.FieldByIndex(attr.idx)
 			newVal := ne.FieldByIndex(attr.idx)
-			if attr.quote {
-				v = unquote(v)
+			err := attr.decode(v, newVal)
+			if err != nil && attr.quote {
+				return errorInvalidValue(res.pointer+"/attributes/"+attr.name, err)
 			}
-			err = json.Unmarshal(v, newVal.Addr().Interface())
 			if err != nil {
 				return err
 			}
+			if err := attr.transform(newVal); err != nil {
+				return err
+			}
 
 			if d.withChanges {
-				d.diff(curVal, newVal, attr.name)
+				d.diff(e1.FieldByIndex(attr.idx), newVal, attr.name)
 			}
 
-			curVal.Set(newVal)
+			set = append(set, attr.idx)
+			submitted[attr.name] = true
 		}
 	}
 
-	if e.Type().Implements(afterUnmarshalerType) {
-		return e.Interface().(AfterUnmarshaler).AfterUnmarshalJSONAPI()
+	for _, m := range f.meta {
+		v, ok := res.Meta[m.name]
+		if !ok || m.readonly || !m.allowed(accessWrite, scope) {
+			continue
+		}
+		fv := ne.FieldByIndex(m.idx)
+		fv.Set(e1.FieldByIndex(m.idx))
+		set = append(set, m.idx)
+		err := m.decode(v, fv)
+		if err != nil && m.quote {
+			return errorInvalidValue(res.pointer+"/meta/"+m.name, err)
+		}
+		if err != nil {
+			return err
+		}
 	}
 
-	return nil
+	for _, l := range f.links {
+		v, ok := res.Links[l.name]
+		if !ok {
+			continue
+		}
+
+		fv := ne.FieldByIndex(l.idx)
+		fv.Set(e1.FieldByIndex(l.idx))
+		set = append(set, l.idx)
+		if fv.Kind() == reflect.String {
+			link := Link{}
+			if err := json.Unmarshal(v, &link); err != nil {
+				return err
+			}
+			fv.SetString(link.Href)
+			continue
+		}
+		if err := json.Unmarshal(v, fv.Addr().Interface()); err != nil {
+			return err
+		}
+	}
+
+	for _, rel := range f.rels {
+		v, ok := res.Relationships[rel.name]
+		if !ok || !rel.allowed(accessWrite, scope) {
+			continue
+		}
+
+		fv := ne.FieldByIndex(rel.idx)
+		if fv.Type() == relationType || !linkage(fv.Type()) {
+			if err := json.Unmarshal(v, fv.Addr().Interface()); err != nil {
+				return err
+			}
+			set = append(set, rel.idx)
+			continue
+		}
+		linked, err := d.unmarshalRelation(v, fv, rel.name, scope)
+		if err != nil {
+			return err
+		}
+		// relationship without data keeps current value
+		if linked {
+			set = append(set, rel.idx)
+		}
+	}
+
+	// fields are set only when all members are decoded
+	for _, idx := range set {
+		e1.FieldByIndex(idx).Set(ne.FieldByIndex(idx))
+	}
+
+	v := Validator{Operation: d.op, submitted: submitted}
+	v.rules(e1, f, scope)
+
+	if m, ok := e.Interface().(UnmarshalValidator); ok {
+		m.ValidateJSONAPI(&v)
+	}
+
+	var err error
+	switch {
+	case e.Type().Implements(afterUnmarshalerType):
+		err = e.Interface().(AfterUnmarshaler).AfterUnmarshalJSONAPI()
+	case e.Type().Implements(afterUnmarshalerCtxType):
+		err = e.Interface().(AfterUnmarshalerContext).AfterUnmarshalJSONAPI(d.ctx)
+	}
+	if !v.HasErrors() {
+		return err
+	}
+	v.AddError(err)
+	v.rebase(res.pointer)
+
+	return v.Verify()
 }
 
-func unquote(b []byte) []byte {
-	l := len(b)
-	if l > 1 && b[0] == '"' && b[l-1] == '"' {
-		return b[1 : l-1]
+// authorize checks submitted attributes and relationships with AttributeWriter
+// and returns 403 errors for every member caller is not allowed to write
+func (d *decoder) authorize(e reflect.Value, f *fields, res *resource, scope Scope) error {
+	writer, ok := e.Interface().(AttributeWriter)
+	if !ok {
+		return nil
+	}
+
+	errs := Errors{}
+	for _, attr := range f.attrs {
+		if _, ok := res.Attributes[attr.name]; !ok || attr.readonly || !attr.allowed(accessWrite, scope) {
+			continue
+		}
+		if !writer.CanWriteAttribute(d.ctx, attr.name) {
+			errs.AddError(errorForbiddenMember(res.pointer + "/attributes/" + attr.name))
+		}
+	}
+	for _, rel := range f.rels {
+		if _, ok := res.Relationships[rel.name]; !ok || !rel.allowed(accessWrite, scope) {
+			continue
+		}
+		if !writer.CanWriteAttribute(d.ctx, rel.name) {
+			errs.AddError(errorForbiddenMember(res.pointer + "/relationships/" + rel.name))
+		}
+	}
+	if errs.HasErrors() {
+		return errs
 	}
-	return b
+	return nil
+}
+
+// errorForbiddenMember returns forbidden error for member pointer
+func errorForbiddenMember(pointer string) Error {
+	e := ErrorForbidden("you are not allowed to change " + pointer[strings.LastIndexByte(pointer, '/')+1:])
+	e.Source = &ErrorSource{Pointer: pointer}
+	return e
+}
+
+// errorInvalidValue returns 422 error for member value which can't be decoded
+func errorInvalidValue(pointer string, err error) error {
+	e := ErrorInvalidAttribute("", "invalid value")
+	e.Code = CodeInvalidValue
+	e.Source.Pointer = pointer
+	e.msg = &message{title: CodeInvalidAttribute, detail: CodeInvalidValue}
+	return Errors{Errors: []Error{e.Wrap(err)}}
 }
 
 type mapKeys struct {
@@ -45,11 +45,62 @@ func assertNil(t *testing.T, v interface{}, descr ...string) {
 	}
 }
 
-func TestUnquote(t *testing.T) {
-	v := unquote([]byte(`""`))
-	assertEqual(t, []byte(""), v)
-	v = unquote([]byte(`"qwe"`))
-	assertEqual(t, []byte("qwe"), v)
+type testStringOption struct {
+	ID      uint64  `jsonapi:"id,strings"`
+	String  string  `jsonapi:"attr,string,string"`
+	Int     int     `jsonapi:"attr,int,string"`
+	Int8    int8    `jsonapi:"attr,int8,string"`
+	Uint64  uint64  `jsonapi:"attr,uint64,string"`
+	Float   float64 `jsonapi:"attr,float,string"`
+	Bool    bool    `jsonapi:"attr,bool,string"`
+	IntPtr  *int    `jsonapi:"attr,int-ptr,string"`
+	NilPtr  *bool   `jsonapi:"attr,nil-ptr,string"`
+	Slice   []int   `jsonapi:"attr,slice,string"`
+	Address testSub `jsonapi:"attr,address,string"`
+	Meta    float32 `jsonapi:"meta,meta,string"`
+}
+
+func TestStringOption(t *testing.T) {
+	n := 7
+	s := testStringOption{
+		ID: 1, String: `say "hi"`, Int: -5, Int8: 8, Uint64: 1 << 63, Float: 1.5, Bool: true,
+		IntPtr: &n, Slice: []int{1}, Address: testSub{City: "C"}, Meta: 2.5,
+	}
+	want := `{"id":"1","type":"strings","attributes":{"string":"\"say \\\"hi\\\"\"","int":"-5","int8":"8","uint64":"9223372036854775808","float":"1.5","bool":"true","int-ptr":"7","nil-ptr":null,"slice":[1],"address":{"country":"","city":"C"}},"meta":{"meta":"2.5"}}`
+	res, err := Marshal(&s)
+	assertNil(t, err)
+	assertEqual(t, want, string(res))
+
+	d := testStringOption{ID: 1}
+	assertNil(t, Unmarshal([]byte(`{"data":`+string(res)+`}`), &d))
+	assertEqual(t, s, d)
+
+	for _, attr := range []string{
+		`"string":"plain"`,
+		`"int":5`,
+		`"int":"5x"`,
+		`"int8":"300"`,
+		`"uint64":"-1"`,
+		`"float":"abc"`,
+		`"bool":"yes"`,
+		`"int-ptr":"\"7\""`,
+	} {
+		err := Unmarshal([]byte(`{"data":{"type":"strings","attributes":{`+attr+`}}}`), &d)
+		errs, ok := err.(Errors)
+		assertEqual(t, true, ok, attr)
+		if ok {
+			assertEqual(t, "422", errs.Errors[0].Status)
+			assertEqual(t, "/data/attributes/"+attr[1:strings.IndexByte(attr[1:], '"')+1], errs.Errors[0].Source.Pointer)
+		}
+	}
+
+	err = Unmarshal([]byte(`{"data":{"type":"strings","attributes":{},"meta":{"meta":"x"}}}`), &d)
+	assertEqual(t, "/data/meta/meta", err.(Errors).Errors[0].Source.Pointer)
+	assertEqual(t, CodeInvalidValue, err.(Errors).Errors[0].Code)
+
+	SetCatalog(MapCatalog{"de": {"invalid_value": "Ungültiger Wert"}})
+	defer SetCatalog(nil)
+	assertEqual(t, "Ungültiger Wert", err.(Errors).Localize("de").Errors[0].Detail)
 }
 
 type testStructUnmarshaler struct {
@@ -157,6 +208,70 @@ func TestUnmarshalWithChanges(t *testing.T) {
 	assertEqual(t, 7, len(changes))
 }
 
+func TestUnmarshalPolymorphicRelations(t *testing.T) {
+	RegisterType(&testPost{}, &testPhoto{})
+
+	req := `{"data":{"id":"1","type":"comments","attributes":{"body":"nice"},"relationships":{` +
+		`"commentable":{"data":{"type":"photos","id":"a1"}},` +
+		`"attachments":{"data":[{"type":"posts","id":"2"},{"type":"photos","id":"b2"}]},` +
+		`"author":{"data":{"type":"posts","id":"3"}}}},` +
+		`"included":[{"id":"a1","type":"photos","attributes":{"url":"/a1.png"}},{"id":"2","type":"posts","attributes":{"title":"T2"}}]}`
+
+	s := testComment{}
+	err := Unmarshal([]byte(req), &s)
+	assertNil(t, err)
+	assertEqual(t, "nice", s.Body)
+	assertEqual(t, &testPhoto{ID: "a1", URL: "/a1.png"}, s.Commentable)
+	assertEqual(t, []interface{}{&testPost{ID: 2, Title: "T2"}, &testPhoto{ID: "b2"}}, s.Attachments)
+	assertEqual(t, &testPost{ID: 3}, s.Author)
+
+	req = `{"data":{"id":"1","type":"comments","attributes":{},"relationships":{"commentable":{"data":{"type":"videos","id":"1"}}}}}`
+	err = Unmarshal([]byte(req), &s)
+	assertEqual(t, "jsonapi: unknown relationship type 'videos'", err.Error())
+
+	req = `{"data":{"id":"1","type":"comments","attributes":{},"relationships":{"commentable":{"data":null}}}}`
+	err = Unmarshal([]byte(req), &s)
+	assertNil(t, err)
+	assertNil(t, s.Commentable)
+}
+
+func TestUnmarshalMeta(t *testing.T) {
+	req := `{"data":{"id":"1","type":"test-metas","attributes":{"name":"A"},"meta":{"version":4,"note":"n"}}}`
+
+	s := testMetaStruct{}
+	err := Unmarshal([]byte(req), &s)
+	assertNil(t, err)
+	assertEqual(t, "A", s.Name)
+	assertEqual(t, 4, s.Version)
+	assertEqual(t, "n", s.Note)
+}
+
+func TestUnmarshalCustomRelation(t *testing.T) {
+	req := `{"data":{"id":"1","type":"customs","attributes":{"name":"A"},"relationships":{"owner":{"data":{"type":"users","id":"2"}}}}}`
+	s := testCustomRelations{}
+	assertNil(t, Unmarshal([]byte(req), &s))
+	assertEqual(t, map[string]string{"type": "users", "id": "2"}, s.Owner.Data)
+}
+
+func TestUnmarshalRelationErrorKeepsFields(t *testing.T) {
+	s := testComment{ID: 1, Body: "old", Author: &testPost{ID: 3}}
+	req := `{"data":{"id":"1","type":"comments","attributes":{"body":"new"},"relationships":{` +
+		`"author":{"data":{"type":"posts","id":"4"}},"commentable":{"data":{"type":"videos","id":"1"}}}}}`
+	err := Unmarshal([]byte(req), &s)
+	assertEqual(t, "jsonapi: unknown relationship type 'videos'", err.Error())
+	assertEqual(t, "old", s.Body)
+	assertEqual(t, &testPost{ID: 3}, s.Author)
+}
+
+func TestUnmarshalRelationWithoutData(t *testing.T) {
+	s := testComment{ID: 1, Body: "old", Author: &testPost{ID: 3}}
+	req := `{"data":{"id":"1","type":"comments","attributes":{"body":"new"},"relationships":{` +
+		`"author":{"links":{"related":"/comments/1/author"},"meta":{"count":1}}}}}`
+	assertNil(t, Unmarshal([]byte(req), &s))
+	assertEqual(t, "new", s.Body)
+	assertEqual(t, &testPost{ID: 3}, s.Author)
+}
+
 type scopeTest struct {
 	ID   uint64 `jsonapi:"id,test-structs"`
 	S1   string `jsonapi:"attr,s1"`
@@ -2,13 +2,63 @@ package jsonapi
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
+	"strings"
+	"sync"
+	"time"
 	"unicode/utf8"
 )
 
+// Operation of unmarshalled request
+type Operation int
+
+// Operations
+const (
+	// OperationUnknown applies all rules to all attributes
+	OperationUnknown Operation = iota
+	// OperationCreate applies required rules to all attributes
+	// and other rules to submitted attributes only
+	OperationCreate
+	// OperationUpdate applies all rules to submitted attributes only
+	OperationUpdate
+)
+
+// UnmarshalValidator interface for validation after unmarshalling.
+// Validator is prepared with operation and submitted attributes.
+// 	func (p *Post) ValidateJSONAPI(v *jsonapi.Validator) {
+// 		v.Present(p.Name, "name") // ignored on update if name was not submitted
+// 	}
+type UnmarshalValidator interface {
+	ValidateJSONAPI(v *Validator)
+}
+
 // Validator structure
 type Validator struct {
 	Errors
+	Operation Operation
+	submitted map[string]bool
+	prefix    string
+}
+
+// Submitted returns true if attribute was present in payload
+// or operation is unknown. Nested pointers are checked by attribute name.
+func (v Validator) Submitted(pointer string) bool {
+	if v.Operation == OperationUnknown {
+		return true
+	}
+	if i := strings.IndexByte(pointer, '/'); i >= 0 {
+		pointer = pointer[:i]
+	}
+	return v.submitted[pointer]
+}
+
+// skip returns true if rule should not be applied to attribute
+func (v Validator) skip(pointer string, required bool) bool {
+	if required && v.Operation == OperationCreate {
+		return false
+	}
+	return !v.Submitted(v.prefix + pointer)
 }
 
 // Verify returns error if errors present and nil if empty
@@ -19,100 +69,288 @@ func (v Validator) Verify() error {
 	return nil
 }
 
+// rebase moves error sources of primary data to resource object at pointer
+func (v *Validator) rebase(pointer string) {
+	if pointer == "/data" {
+		return
+	}
+	for i, e := range v.Errors.Errors {
+		if e.Source == nil || !strings.HasPrefix(e.Source.Pointer, "/data/") {
+			continue
+		}
+		src := *e.Source
+		src.Pointer = pointer + src.Pointer[len("/data"):]
+		v.Errors.Errors[i].Source = &src
+	}
+}
+
+// Invalid adds invalid attribute error with rule code, detail and arguments for localization
+// 	v.Invalid("password-confirmation", "password_mismatch", "does not match password")
+func (v *Validator) Invalid(pointer, code, detail string, args ...interface{}) {
+	e := ErrorInvalidAttribute(v.prefix+pointer, detail)
+	e.Code = code
+	e.msg = &message{title: CodeInvalidAttribute, detail: code, args: args}
+	v.AddError(e)
+}
+
 // Present validates string for presence
 // 	v.Present(SomeVariable, "name")
 func (v *Validator) Present(value, pointer string) {
+	if v.skip(pointer, true) {
+		return
+	}
 	if value == "" {
-		v.AddError(ErrorInvalidAttribute(pointer, pointer+" required"))
+		v.Invalid(pointer, CodeRequired, pointer+" required", pointer)
 	}
 }
 
 // StringLength validates string min, max length. -1 for any
 // 	v.StringLength(SomeVariable, "password", 6, 18) // min 6, max 18
 func (v *Validator) StringLength(value, pointer string, min, max int) {
+	if v.skip(pointer, false) {
+		return
+	}
 	if min > 0 && utf8.RuneCountInString(value) < min {
-		v.AddError(ErrorInvalidAttribute(pointer, fmt.Sprint("min length is", min)))
+		v.Invalid(pointer, CodeMinLength, fmt.Sprint("min length is", min), min)
 	}
 	if max > 0 && utf8.RuneCountInString(value) > max {
-		v.AddError(ErrorInvalidAttribute(pointer, fmt.Sprint("max length is", max)))
+		v.Invalid(pointer, CodeMaxLength, fmt.Sprint("max length is", max), max)
 	}
 }
 
-// Int validates int min, max. -1 for any
+// Int validates int min, max. 0 or less for any
 // 	v.Int(IntValue, "number", -1, 11)  // max 18
+//
+// Deprecated: use Range which supports zero, negative and exclusive bounds
 func (v *Validator) Int(value int, pointer string, min, max int) {
-	if min > 0 && value < min {
-		v.AddError(ErrorInvalidAttribute(pointer, fmt.Sprint("min value is", min)))
-	}
-	if max > 0 && value > max {
-		v.AddError(ErrorInvalidAttribute(pointer, fmt.Sprint("max value is", max)))
-	}
+	v.Range(value, pointer, legacyBound(min, min > 0), legacyBound(max, max > 0))
 }
 
 // Int64Present validates if value is not 0
 // 	v.Int64Present(Int64Value, "number")
 func (v *Validator) Int64Present(value int64, pointer string) {
+	if v.skip(pointer, true) {
+		return
+	}
 	if value == 0 {
-		v.AddError(ErrorInvalidAttribute(pointer, pointer+" required"))
+		v.Invalid(pointer, CodeRequired, pointer+" required", pointer)
 	}
 }
 
-// Int64 validates int min, max. -1 for any
+// Int64 validates int64 min, max. 0 or less for any
 // 	v.Int64(Int64Value, "number", -1, 11)  // max 18
+//
+// Deprecated: use Range which supports zero, negative and exclusive bounds
 func (v *Validator) Int64(value int64, pointer string, min, max int64) {
-	if min > 0 && value < min {
-		v.AddError(ErrorInvalidAttribute(pointer, fmt.Sprint("min value is", min)))
-	}
-	if max > 0 && value > max {
-		v.AddError(ErrorInvalidAttribute(pointer, fmt.Sprint("max value is", max)))
-	}
+	v.Range(value, pointer, legacyBound(min, min > 0), legacyBound(max, max > 0))
 }
 
 // Uint64Present validates if value is not 0
 // 	v.Uint64Present(Uint64Value, "number")
 func (v *Validator) Uint64Present(value uint64, pointer string) {
+	if v.skip(pointer, true) {
+		return
+	}
 	if value == 0 {
-		v.AddError(ErrorInvalidAttribute(pointer, pointer+" required"))
+		v.Invalid(pointer, CodeRequired, pointer+" required", pointer)
 	}
 }
 
-// Uint64 validates int min, max. -1 for any
+// Uint64 validates uint64 min, max. 0 or less for any
 // 	v.Uint64(Uint64Value, "number", -1, 11)  // max 18
+//
+// Deprecated: use Range which supports zero, negative and exclusive bounds
 func (v *Validator) Uint64(value uint64, pointer string, min, max int) {
-	if min > 0 && value < uint64(min) {
-		v.AddError(ErrorInvalidAttribute(pointer, fmt.Sprint("min value is", min)))
-	}
-	if max > 0 && value > uint64(max) {
-		v.AddError(ErrorInvalidAttribute(pointer, fmt.Sprint("max value is", max)))
-	}
+	v.Range(value, pointer, legacyBound(min, min > 0), legacyBound(max, max > 0))
 }
 
-// Float32 validates int min, max. -1 for any
+// Float32 validates float32 min, max. 0 or less for any
 // 	v.Float32(Float32Value, "number", -1, 11)  // max 18
+//
+// Deprecated: use Range which supports zero, negative and exclusive bounds
 func (v *Validator) Float32(value float32, pointer string, min, max float32) {
-	if min > 0 && value < min {
-		v.AddError(ErrorInvalidAttribute(pointer, fmt.Sprint("min value is", min)))
-	}
-	if max > 0 && value > max {
-		v.AddError(ErrorInvalidAttribute(pointer, fmt.Sprint("max value is", max)))
-	}
+	v.Range(value, pointer, legacyBound(min, min > 0), legacyBound(max, max > 0))
 }
 
-// Float64 validates int min, max. -1 for any
+// Float64 validates float64 min, max. 0 or less for any
 // 	v.Float64(Float64Value, "number", -1, 11)  // max 18
+//
+// Deprecated: use Range which supports zero, negative and exclusive bounds
 func (v *Validator) Float64(value float64, pointer string, min, max float64) {
-	if min > 0 && value < min {
-		v.AddError(ErrorInvalidAttribute(pointer, fmt.Sprint("min value is", min)))
-	}
-	if max > 0 && value > max {
-		v.AddError(ErrorInvalidAttribute(pointer, fmt.Sprint("max value is", max)))
-	}
+	v.Range(value, pointer, legacyBound(min, min > 0), legacyBound(max, max > 0))
 }
 
 // Format validates string format with regex string
 // 	v.Format(StringValue,"ip address", `\A(\d{1,3}\.){3}\d{1,3}\z`)
 func (v *Validator) Format(value, pointer, reg string) {
-	if r, _ := regexp.MatchString(reg, value); !r {
-		v.AddError(ErrorInvalidAttribute(pointer, "invalid format"))
+	if v.skip(pointer, false) {
+		return
 	}
+	r, err := compileRegexp(reg)
+	if err != nil || !r.MatchString(value) {
+		v.Invalid(pointer, CodeInvalidFormat, "invalid format")
+	}
+}
+
+var regexps = regexpCache{m: make(map[string]*regexp.Regexp)}
+
+type regexpCache struct {
+	sync.RWMutex
+	m map[string]*regexp.Regexp
+}
+
+// compileRegexp returns cached compiled regexp
+func compileRegexp(s string) (*regexp.Regexp, error) {
+	regexps.RLock()
+	r, ok := regexps.m[s]
+	regexps.RUnlock()
+	if ok {
+		return r, nil
+	}
+
+	r, err := regexp.Compile(s)
+	if err != nil {
+		return nil, err
+	}
+	regexps.Lock()
+	regexps.m[s] = r
+	regexps.Unlock()
+	return r, nil
+}
+
+// Email validates email address. Empty value is ignored.
+// 	v.Email(StringValue, "email")
+func (v *Validator) Email(value, pointer string) {
+	if v.skip(pointer, false) || value == "" {
+		return
+	}
+	if !validEmail(value) {
+		v.Invalid(pointer, CodeEmail, "invalid email")
+	}
+}
+
+// URL validates absolute url. Empty value is ignored.
+// 	v.URL(StringValue, "site")
+func (v *Validator) URL(value, pointer string) {
+	if v.skip(pointer, false) || value == "" {
+		return
+	}
+	if !validURL(value) {
+		v.Invalid(pointer, CodeURL, "invalid url")
+	}
+}
+
+// UUID validates uuid string. Empty value is ignored.
+// 	v.UUID(StringValue, "reference")
+func (v *Validator) UUID(value, pointer string) {
+	if v.skip(pointer, false) || value == "" {
+		return
+	}
+	if !uuidRegexp.MatchString(value) {
+		v.Invalid(pointer, CodeUUID, "invalid uuid")
+	}
+}
+
+// OneOf validates value is one of allowed values
+// 	v.OneOf(StringValue, "role", "admin", "user")
+func (v *Validator) OneOf(value, pointer string, values ...string) {
+	if v.skip(pointer, false) {
+		return
+	}
+	for _, s := range values {
+		if s == value {
+			return
+		}
+	}
+	v.Invalid(pointer, CodeOneOf, "must be one of: "+strings.Join(values, ", "), strings.Join(values, ", "))
+}
+
+// Before validates time is before t
+// 	v.Before(TimeValue, "starts-at", time.Now())
+func (v *Validator) Before(value time.Time, pointer string, t time.Time) {
+	if v.skip(pointer, false) {
+		return
+	}
+	if !value.Before(t) {
+		s := t.Format(time.RFC3339)
+		v.Invalid(pointer, CodeTimeBefore, "must be before "+s, s)
+	}
+}
+
+// After validates time is after t
+// 	v.After(TimeValue, "ends-at", StartsAt)
+func (v *Validator) After(value time.Time, pointer string, t time.Time) {
+	if v.skip(pointer, false) {
+		return
+	}
+	if !value.After(t) {
+		s := t.Format(time.RFC3339)
+		v.Invalid(pointer, CodeTimeAfter, "must be after "+s, s)
+	}
+}
+
+// TimeRange validates time is within min and max inclusive. Zero time for any
+// 	v.TimeRange(TimeValue, "birthday", time.Time{}, time.Now())
+func (v *Validator) TimeRange(value time.Time, pointer string, min, max time.Time) {
+	if v.skip(pointer, false) {
+		return
+	}
+	if !min.IsZero() && value.Before(min) {
+		s := min.Format(time.RFC3339)
+		v.Invalid(pointer, CodeTimeMin, "min time is "+s, s)
+	}
+	if !max.IsZero() && value.After(max) {
+		s := max.Format(time.RFC3339)
+		v.Invalid(pointer, CodeTimeMax, "max time is "+s, s)
+	}
+}
+
+// SliceLength validates slice, array or map min, max length. -1 for any
+// 	v.SliceLength(Tags, "tags", 1, 10)
+func (v *Validator) SliceLength(value interface{}, pointer string, min, max int) {
+	if v.skip(pointer, false) {
+		return
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+	default:
+		return
+	}
+	if min > 0 && rv.Len() < min {
+		v.Invalid(pointer, CodeMinItems, fmt.Sprintf("must have at least %d items", min), min)
+	}
+	if max > 0 && rv.Len() > max {
+		v.Invalid(pointer, CodeMaxItems, fmt.Sprintf("must have at most %d items", max), max)
+	}
+}
+
+// Unique validates slice or array items are unique
+// 	v.Unique(Tags, "tags")
+func (v *Validator) Unique(value interface{}, pointer string) {
+	if v.skip(pointer, false) {
+		return
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return
+	}
+	for i := 0; i < rv.Len(); i++ {
+		for j := i + 1; j < rv.Len(); j++ {
+			if reflect.DeepEqual(rv.Index(i).Interface(), rv.Index(j).Interface()) {
+				v.Invalid(pointer, CodeUnique, "items must be unique")
+				return
+			}
+		}
+	}
+}
+
+// Nested validates nested object. Pointers of nested validator are relative to pointer.
+// 	v.Nested("address", func(n *jsonapi.Validator) {
+// 		n.Present(p.Address.City, "city") // pointer is /data/attributes/address/city
+// 	})
+func (v *Validator) Nested(pointer string, fn func(n *Validator)) {
+	n := Validator{Operation: v.Operation, submitted: v.submitted, prefix: v.prefix + pointer + "/"}
+	fn(&n)
+	v.Errors.Errors = append(v.Errors.Errors, n.Errors.Errors...)
 }
@@ -0,0 +1,120 @@
+package jsonapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidatorRules(t *testing.T) {
+	now := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	v := Validator{}
+	v.Email("john@example.com", "email")
+	v.Email("john", "email")
+	v.URL("https://example.com/a", "site")
+	v.URL("example.com", "site")
+	v.UUID("0F8FAD5B-D9CB-469F-A165-70867728950E", "ref")
+	v.UUID("0f8fad5b", "ref")
+	v.OneOf("user", "role", "admin", "user")
+	v.OneOf("guest", "role", "admin", "user")
+	v.Before(now, "starts", now)
+	v.After(now, "ends", now.Add(-time.Hour))
+	v.TimeRange(now, "at", now.Add(time.Hour), time.Time{})
+	v.TimeRange(now, "at", time.Time{}, now.Add(-time.Hour))
+	v.SliceLength([]int{1}, "tags", 2, 3)
+	v.SliceLength([]int{1, 2, 3, 4}, "tags", -1, 3)
+	v.Unique([]string{"a", "b", "a"}, "tags")
+	v.Unique([]string{"a", "b"}, "tags")
+	v.Format("1.1.1", "ip", `\A(\d{1,3}\.){3}\d{1,3}\z`)
+	v.Format("1.1.1", "ip", `(`)
+	v.Nested("address", func(n *Validator) {
+		n.Present("", "city")
+	})
+
+	want := []string{
+		"/data/attributes/email email",
+		"/data/attributes/site url",
+		"/data/attributes/ref uuid",
+		"/data/attributes/role one_of",
+		"/data/attributes/starts time_before",
+		"/data/attributes/at time_min",
+		"/data/attributes/at time_max",
+		"/data/attributes/tags min_items",
+		"/data/attributes/tags max_items",
+		"/data/attributes/tags unique",
+		"/data/attributes/ip invalid_format",
+		"/data/attributes/ip invalid_format",
+		"/data/attributes/address/city required",
+	}
+	assertEqual(t, want, errorPointers(v.Verify()))
+	assertEqual(t, "must be before 2020-01-02T00:00:00Z", v.Errors.Errors[4].Detail)
+}
+
+func TestValidatorRange(t *testing.T) {
+	v := Validator{}
+	v.Range(-5, "level", Inclusive(-10), Inclusive(0))
+	v.Range(-11, "level", Inclusive(-10), Inclusive(0))
+	v.Range(1, "level", Inclusive(-10), Inclusive(0))
+	v.Range(0, "count", Inclusive(0), Bound{})
+	v.Range(int8(-1), "count", Inclusive(0), Bound{})
+	v.Range(uint8(255), "byte", Bound{}, Inclusive(uint8(255)))
+	v.Range(uint64(1<<63), "big", Inclusive(int64(-1)), Bound{})
+	v.Range(0.0, "price", Exclusive(0), Bound{})
+	v.Range(float32(9.5), "rate", Bound{}, Exclusive(10))
+	v.Range(10, "rate", Bound{}, Exclusive(10.0))
+	v.Int(0, "legacy", 0, 0)
+	v.Uint64(20, "legacy", 1, 10)
+
+	want := []string{
+		"/data/attributes/level min_value",
+		"/data/attributes/level max_value",
+		"/data/attributes/count min_value",
+		"/data/attributes/price greater_than",
+		"/data/attributes/rate less_than",
+		"/data/attributes/legacy max_value",
+	}
+	assertEqual(t, want, errorPointers(v.Verify()))
+	assertEqual(t, "min value is-10", v.Errors.Errors[0].Detail)
+	assertEqual(t, "max value is10", v.Errors.Errors[5].Detail)
+}
+
+func TestValidatorRangeInvalidValue(t *testing.T) {
+	v := Validator{}
+	v.Range((*int)(nil), "level", Inclusive(0), Bound{})
+	assertNil(t, v.Verify())
+
+	defer func() {
+		assertEqual(t, "jsonapi: invalid range value string, number expected", recover())
+	}()
+	v.Range("5", "level", Inclusive(0), Bound{})
+}
+
+type testAddress struct {
+	City   string `json:"city" validate:"required"`
+	Zip    string `json:"zip,omitempty" validate:"len=5"`
+	Ignore string `json:"-" validate:"required"`
+}
+
+type testNested struct {
+	ID        uint64        `jsonapi:"id,test-nested"`
+	Name      string        `jsonapi:"attr,name"`
+	Address   testAddress   `jsonapi:"attr,address"`
+	Addresses []testAddress `jsonapi:"attr,addresses"`
+	Other     *testAddress  `jsonapi:"attr,other"`
+}
+
+func TestValidateNested(t *testing.T) {
+	s := testNested{
+		Address:   testAddress{City: "A", Zip: "12345"},
+		Addresses: []testAddress{{City: "B", Zip: "12345"}, {Zip: "1"}},
+	}
+	want := []string{
+		"/data/attributes/addresses/1/city required",
+		"/data/attributes/addresses/1/zip length",
+	}
+	assertEqual(t, want, errorPointers(Validate(&s)))
+
+	req := `{"data":{"type":"test-nested","attributes":{"name":"n","other":{"city":"","zip":"12345"}}}}`
+	s = testNested{Address: testAddress{Zip: "1"}}
+	_, err := UnmarshalWithOptions([]byte(req), &s, UnmarshalOptions{Operation: OperationUpdate})
+	assertEqual(t, []string{"/data/attributes/other/city required"}, errorPointers(err))
+}